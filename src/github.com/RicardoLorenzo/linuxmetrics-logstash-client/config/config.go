@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -49,6 +50,31 @@ func (config *Config) HasProperty(name string) bool {
 	return present
 }
 
+func (config *Config) GetIntProperty(name string, defaultValue int) int {
+	if config.HasProperty(name) {
+		if value, err := strconv.Atoi(config.properties[name]); err == nil {
+			return value
+		}
+	}
+	return defaultValue
+}
+
+/*
+ * Comma separated list properties, empty items are discarded
+ */
+func (config *Config) GetListProperty(name string, defaultValue []string) []string {
+	if !config.HasProperty(name) {
+		return defaultValue
+	}
+	var values []string
+	for _, value := range strings.Split(config.properties[name], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func (config *Config) SetProperty(name string, value string) {
 	config.properties[name] = value
 }
@@ -56,7 +82,7 @@ func (config *Config) SetProperty(name string, value string) {
 func (config *Config) LoadProperties() error {
 	config.properties = make(map[string]string)
 
-	if _, err := os.Stat(config.path); os.IsExist(err) {
+	if _, err := os.Stat(config.path); err == nil {
 		file, err := os.Open(config.path)
 		if err != nil {
 			return &ConfigError{fmt.Sprint(err), err}
@@ -65,8 +91,15 @@ func (config *Config) LoadProperties() error {
 
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
-			tokens := strings.Split(scanner.Text(), "=")
-			config.properties[tokens[0]] = tokens[1]
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			tokens := strings.SplitN(line, "=", 2)
+			if len(tokens) != 2 {
+				continue
+			}
+			config.properties[strings.TrimSpace(tokens[0])] = strings.TrimSpace(tokens[1])
 		}
 
 		if err := scanner.Err(); err != nil {
@@ -0,0 +1,254 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	stats "github.com/RicardoLorenzo/linuxmetrics-logstash-client/stats"
+)
+
+const (
+	composeProjectLabel string = "com.docker.compose.project"
+	// The host part is ignored, requests always go through the socket
+	baseURL string = "http://docker"
+)
+
+// Entry of GET /containers/json
+type container struct {
+	Id     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// Document of GET /containers/<id>/json
+type containerInspect struct {
+	Id     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// Entry of the GET /events stream
+type event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+type DockerClient struct {
+	SocketPath string
+	// Full reload interval, on top of the reloads triggered by events
+	RefreshInterval time.Duration
+	// Requests time out, the event stream does not
+	HttpClient   *http.Client
+	eventsClient *http.Client
+	containers   map[string]*stats.ContainerMetadata
+	// Containers read (true) or removed (false) by events since the
+	// running reload requested the list, nil outside of reloads
+	eventUpdates map[string]bool
+	rwlock       sync.RWMutex
+}
+
+/**
+ * Client of the Docker Engine API listening on a Unix socket, usually
+ * /var/run/docker.sock
+ */
+func NewDockerClient(socketPath string, refreshInterval time.Duration) *DockerClient {
+	client := DockerClient{}
+	client.SocketPath = socketPath
+	client.RefreshInterval = refreshInterval
+	client.containers = make(map[string]*stats.ContainerMetadata)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: 5 * time.Second}
+			return dialer.DialContext(ctx, "unix", client.SocketPath)
+		},
+	}
+	client.HttpClient = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	client.eventsClient = &http.Client{Transport: transport}
+	return &client
+}
+
+func (client *DockerClient) get(httpClient *http.Client, path string) (*http.Response, error) {
+	response, err := httpClient.Get(baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("Docker request %s failed - %s", path, response.Status)
+	}
+	return response, nil
+}
+
+func (client *DockerClient) getContainerName(names []string) string {
+	// Names are prefixed with a slash, like "/web_1"
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(names[0], "/")
+}
+
+func (client *DockerClient) newContainerMetadata(name, image string, labels map[string]string) *stats.ContainerMetadata {
+	return &stats.ContainerMetadata{
+		Name:           name,
+		Image:          image,
+		Labels:         labels,
+		ComposeProject: labels[composeProjectLabel],
+	}
+}
+
+/**
+ * Reloads the running containers into the cache. Events received while
+ * the list is requested are newer than it and take precedence.
+ */
+func (client *DockerClient) Update() error {
+	client.rwlock.Lock()
+	client.eventUpdates = make(map[string]bool)
+	client.rwlock.Unlock()
+	defer func() {
+		client.rwlock.Lock()
+		client.eventUpdates = nil
+		client.rwlock.Unlock()
+	}()
+
+	response, err := client.get(client.HttpClient, "/containers/json")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	list := []container{}
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		return err
+	}
+
+	client.rwlock.Lock()
+	defer client.rwlock.Unlock()
+	listed := make(map[string]bool)
+	for _, container := range list {
+		listed[container.Id] = true
+		if _, present := client.eventUpdates[container.Id]; present {
+			continue
+		}
+		client.containers[container.Id] = client.newContainerMetadata(client.getContainerName(container.Names),
+			container.Image, container.Labels)
+	}
+	for containerId := range client.containers {
+		if !listed[containerId] && !client.eventUpdates[containerId] {
+			delete(client.containers, containerId)
+		}
+	}
+	return nil
+}
+
+/**
+ * Reads a single container into the cache, for the containers listed in
+ * events between two reloads
+ */
+func (client *DockerClient) updateContainer(containerId string) error {
+	response, err := client.get(client.HttpClient, "/containers/"+url.PathEscape(containerId)+"/json")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	inspect := containerInspect{}
+	if err := json.NewDecoder(response.Body).Decode(&inspect); err != nil {
+		return err
+	}
+
+	client.rwlock.Lock()
+	defer client.rwlock.Unlock()
+	client.containers[inspect.Id] = client.newContainerMetadata(strings.TrimPrefix(inspect.Name, "/"),
+		inspect.Config.Image, inspect.Config.Labels)
+	if client.eventUpdates != nil {
+		client.eventUpdates[inspect.Id] = true
+	}
+	return nil
+}
+
+func (client *DockerClient) removeContainer(containerId string) {
+	client.rwlock.Lock()
+	defer client.rwlock.Unlock()
+	delete(client.containers, containerId)
+	if client.eventUpdates != nil {
+		client.eventUpdates[containerId] = false
+	}
+}
+
+/**
+ * Blocks reading container events, a container is read again whenever
+ * it is created, started or renamed, and dropped once removed. Returns
+ * when the stream is closed.
+ */
+func (client *DockerClient) watchEvents() error {
+	filters := url.QueryEscape(`{"type":["container"]}`)
+	response, err := client.get(client.eventsClient, "/events?filters="+filters)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		receivedEvent := event{}
+		if err := decoder.Decode(&receivedEvent); err != nil {
+			return err
+		}
+		switch receivedEvent.Action {
+		case "create", "start", "rename":
+			if err := client.updateContainer(receivedEvent.Actor.ID); err != nil {
+				log.Println("Docker metadata refresh has failed - ", fmt.Sprint(err))
+			}
+		case "destroy":
+			client.removeContainer(receivedEvent.Actor.ID)
+		}
+	}
+}
+
+/**
+ * Background loops keeping the cache up to date. Events are watched
+ * while the daemon is reachable, with a full reload every refresh
+ * interval in case an event was missed.
+ */
+func (client *DockerClient) RefreshMetadata() {
+	go func() {
+		for {
+			if err := client.watchEvents(); err != nil {
+				log.Println("Docker event stream has been interrupted - ", fmt.Sprint(err))
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	for {
+		if err := client.Update(); err != nil {
+			log.Println("Docker metadata refresh has failed - ", fmt.Sprint(err))
+		}
+		time.Sleep(client.RefreshInterval)
+	}
+}
+
+func (client *DockerClient) GetContainerMetadata(containerId, podUid string) *stats.ContainerMetadata {
+	client.rwlock.RLock()
+	defer client.rwlock.RUnlock()
+
+	if metadata, present := client.containers[containerId]; present {
+		return metadata
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package docker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const containersDocument = `[
+  {
+    "Id": "aaa111",
+    "Names": ["/shop_web_1"],
+    "Image": "nginx:1.21",
+    "Labels": {"com.docker.compose.project": "shop", "tier": "front"}
+  },
+  {
+    "Id": "bbb222",
+    "Names": ["/db"],
+    "Image": "postgres:13",
+    "Labels": {}
+  }
+]`
+
+const inspectDocument = `{
+  "Id": "ccc333",
+  "Name": "/worker",
+  "Config": {"Image": "worker:2", "Labels": {"com.docker.compose.project": "jobs"}}
+}`
+
+/**
+ * Docker Engine API stand-in listening on a Unix socket. Events written
+ * to the events channel are streamed to the /events client.
+ */
+type fakeDocker struct {
+	server *httptest.Server
+	socket string
+	events chan string
+	// Closed to release the requests blocked on /containers/json
+	release     chan bool
+	releaseOnce sync.Once
+	hang        bool
+}
+
+func newFakeDocker(t *testing.T, hang bool) *fakeDocker {
+	directory, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeDocker{
+		socket:  filepath.Join(directory, "docker.sock"),
+		events:  make(chan string),
+		release: make(chan bool),
+		hang:    hang,
+	}
+	listener, err := net.Listen("unix", fake.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.server = httptest.NewUnstartedServer(fake)
+	fake.server.Listener = listener
+	fake.server.Start()
+	return fake
+}
+
+func (fake *fakeDocker) releaseList() {
+	fake.releaseOnce.Do(func() { close(fake.release) })
+}
+
+func (fake *fakeDocker) Close() {
+	fake.releaseList()
+	close(fake.events)
+	fake.server.Close()
+	os.RemoveAll(filepath.Dir(fake.socket))
+}
+
+func (fake *fakeDocker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/containers/json":
+		if fake.hang {
+			<-fake.release
+		}
+		writer.Write([]byte(containersDocument))
+	case "/containers/ccc333/json":
+		writer.Write([]byte(inspectDocument))
+	case "/events":
+		filters := map[string][]string{}
+		if err := json.Unmarshal([]byte(request.URL.Query().Get("filters")), &filters); err != nil ||
+			len(filters["type"]) != 1 || filters["type"][0] != "container" {
+			http.Error(writer, "unexpected filters", http.StatusBadRequest)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+		writer.(http.Flusher).Flush()
+		for event := range fake.events {
+			writer.Write([]byte(event + "\n"))
+			writer.(http.Flusher).Flush()
+		}
+	default:
+		http.NotFound(writer, request)
+	}
+}
+
+// Waits for the cache of the event goroutine to reach the expected state
+func waitForMetadata(t *testing.T, client *DockerClient, containerId string, present bool) {
+	for i := 0; i < 100; i++ {
+		if (client.GetContainerMetadata(containerId, "") != nil) == present {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("container %s present should be %v", containerId, present)
+}
+
+func TestUpdate(t *testing.T) {
+	fake := newFakeDocker(t, false)
+	defer fake.Close()
+
+	client := NewDockerClient(fake.socket, time.Minute)
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	metadata := client.GetContainerMetadata("aaa111", "")
+	if metadata == nil || metadata.Name != "shop_web_1" || metadata.Image != "nginx:1.21" ||
+		metadata.ComposeProject != "shop" || metadata.Labels["tier"] != "front" {
+		t.Errorf("container aaa111 has metadata %+v", metadata)
+	}
+	if metadata := client.GetContainerMetadata("bbb222", ""); metadata == nil || metadata.ComposeProject != "" {
+		t.Errorf("container bbb222 has metadata %+v", metadata)
+	}
+	if metadata := client.GetContainerMetadata("unknown", ""); metadata != nil {
+		t.Errorf("unknown container has metadata %+v", metadata)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	fake := newFakeDocker(t, false)
+	defer fake.Close()
+
+	client := NewDockerClient(fake.socket, time.Minute)
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	go client.watchEvents()
+
+	fake.events <- `{"Type":"container","Action":"start","Actor":{"ID":"ccc333"}}`
+	waitForMetadata(t, client, "ccc333", true)
+	metadata := client.GetContainerMetadata("ccc333", "")
+	if metadata.Name != "worker" || metadata.Image != "worker:2" || metadata.ComposeProject != "jobs" {
+		t.Errorf("container ccc333 has metadata %+v", metadata)
+	}
+
+	fake.events <- `{"Type":"container","Action":"destroy","Actor":{"ID":"aaa111"}}`
+	waitForMetadata(t, client, "aaa111", false)
+	if client.GetContainerMetadata("bbb222", "") == nil {
+		t.Error("container bbb222 dropped by an event of another container")
+	}
+}
+
+func TestEventsDuringUpdate(t *testing.T) {
+	fake := newFakeDocker(t, true)
+	defer fake.Close()
+
+	client := NewDockerClient(fake.socket, time.Minute)
+	client.containers["ddd444"] = client.newContainerMetadata("stale", "busybox:1", nil)
+	go client.watchEvents()
+	done := make(chan error)
+	go func() {
+		done <- client.Update()
+	}()
+	for i := 0; ; i++ {
+		client.rwlock.RLock()
+		started := client.eventUpdates != nil
+		client.rwlock.RUnlock()
+		if started {
+			break
+		}
+		if i == 100 {
+			t.Fatal("Update has not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Both events are newer than the list still being answered
+	fake.events <- `{"Type":"container","Action":"start","Actor":{"ID":"ccc333"}}`
+	waitForMetadata(t, client, "ccc333", true)
+	fake.events <- `{"Type":"container","Action":"destroy","Actor":{"ID":"aaa111"}}`
+	waitForMetadata(t, client, "aaa111", false)
+	fake.releaseList()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if client.GetContainerMetadata("ccc333", "") == nil {
+		t.Error("container started during the reload dropped")
+	}
+	if metadata := client.GetContainerMetadata("aaa111", ""); metadata != nil {
+		t.Errorf("container destroyed during the reload restored %+v", metadata)
+	}
+	if client.GetContainerMetadata("bbb222", "") == nil {
+		t.Error("listed container bbb222 missing")
+	}
+	if metadata := client.GetContainerMetadata("ddd444", ""); metadata != nil {
+		t.Errorf("container no longer listed kept %+v", metadata)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	fake := newFakeDocker(t, true)
+	defer fake.Close()
+
+	client := NewDockerClient(fake.socket, time.Minute)
+	client.HttpClient.Timeout = 100 * time.Millisecond
+	done := make(chan error)
+	go func() {
+		done <- client.Update()
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("no error from a daemon that never answers")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update blocked on a daemon that never answers")
+	}
+}
@@ -0,0 +1,226 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	stats "github.com/RicardoLorenzo/linuxmetrics-logstash-client/stats"
+)
+
+const (
+	SourceKubelet   string = "kubelet"
+	SourceAPIServer string = "apiserver"
+
+	serviceAccountPath string = "/var/run/secrets/kubernetes.io/serviceaccount/"
+)
+
+/**
+ * The subset of a v1.PodList used for enrichment, the same document is
+ * returned by the kubelet /pods endpoint and the API server.
+ */
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+type pod struct {
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		UID       string            `json:"uid"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		ContainerStatuses          []containerStatus `json:"containerStatuses"`
+		InitContainerStatuses      []containerStatus `json:"initContainerStatuses"`
+		EphemeralContainerStatuses []containerStatus `json:"ephemeralContainerStatuses"`
+	} `json:"status"`
+}
+
+type containerStatus struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// <runtime>://<id>, like containerd://0123...
+	ContainerID string `json:"containerID"`
+}
+
+type KubernetesClient struct {
+	// Pod list endpoint, either the kubelet or the API server
+	PodsURL         string
+	Token           string
+	RefreshInterval time.Duration
+	HttpClient      *http.Client
+	containers      map[string]*stats.ContainerMetadata
+	pods            map[string]*stats.ContainerMetadata
+	rwlock          sync.RWMutex
+}
+
+/**
+ * Client reading the pods of nodeName from the kubelet (kubeletURL,
+ * usually https://127.0.0.1:10250) or from the API server found
+ * through the in-cluster environment.
+ */
+func NewKubernetesClient(source, kubeletURL, nodeName string, refreshInterval time.Duration, insecure bool) (*KubernetesClient, error) {
+	client := KubernetesClient{}
+	client.RefreshInterval = refreshInterval
+	client.containers = make(map[string]*stats.ContainerMetadata)
+	client.pods = make(map[string]*stats.ContainerMetadata)
+
+	switch source {
+	case SourceKubelet:
+		client.PodsURL = strings.TrimSuffix(kubeletURL, "/") + "/pods"
+	case SourceAPIServer:
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		port := os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("Kubernetes API server environment is not defined")
+		}
+		client.PodsURL = fmt.Sprintf("https://%s:%s/api/v1/pods?fieldSelector=%s", host, port,
+			url.QueryEscape("spec.nodeName="+nodeName))
+	default:
+		return nil, fmt.Errorf("Unknown Kubernetes metadata source %q", source)
+	}
+
+	if token, err := ioutil.ReadFile(serviceAccountPath + "token"); err == nil {
+		client.Token = strings.TrimSpace(string(token))
+	}
+
+	client.HttpClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: newTLSConfig(serviceAccountPath+"ca.crt", insecure)},
+	}
+	return &client, nil
+}
+
+/**
+ * Server certificates are verified against the cluster CA of the service
+ * account, which signs the kubelet serving certificates when they are
+ * bootstrapped through the API server. Self-signed kubelet certificates
+ * need insecure, as the bearer token is otherwise sent unverified.
+ */
+func newTLSConfig(caPath string, insecure bool) *tls.Config {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if insecure {
+		log.Println("Kubernetes server certificates are not verified")
+		return tlsConfig
+	}
+
+	certificates, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		log.Println("Cannot read the cluster CA, using the system ones -", err)
+		return tlsConfig
+	}
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(certificates) {
+		log.Println("No certificate found in", caPath)
+	}
+	return tlsConfig
+}
+
+func (client *KubernetesClient) getPods() (*podList, error) {
+	request, err := http.NewRequest("GET", client.PodsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.Token)
+	}
+	response, err := client.HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Pod list request to %s failed - %s", client.PodsURL, response.Status)
+	}
+	pods := podList{}
+	if err := json.NewDecoder(response.Body).Decode(&pods); err != nil {
+		return nil, err
+	}
+	return &pods, nil
+}
+
+func getContainerId(containerID string) string {
+	if index := strings.Index(containerID, "://"); index >= 0 {
+		return containerID[index+3:]
+	}
+	return containerID
+}
+
+/**
+ * Reloads the pods of this node and replaces the cache
+ */
+func (client *KubernetesClient) Update() error {
+	pods, err := client.getPods()
+	if err != nil {
+		return err
+	}
+
+	containers := make(map[string]*stats.ContainerMetadata)
+	podsByUid := make(map[string]*stats.ContainerMetadata)
+	for _, pod := range pods.Items {
+		podsByUid[pod.Metadata.UID] = &stats.ContainerMetadata{
+			Namespace: pod.Metadata.Namespace,
+			PodName:   pod.Metadata.Name,
+			PodLabels: pod.Metadata.Labels,
+		}
+
+		statuses := append(pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+		for _, status := range statuses {
+			if status.ContainerID == "" {
+				continue
+			}
+			containers[getContainerId(status.ContainerID)] = &stats.ContainerMetadata{
+				Name:      status.Name,
+				Image:     status.Image,
+				Namespace: pod.Metadata.Namespace,
+				PodName:   pod.Metadata.Name,
+				PodLabels: pod.Metadata.Labels,
+			}
+		}
+	}
+
+	client.rwlock.Lock()
+	defer client.rwlock.Unlock()
+	client.containers = containers
+	client.pods = podsByUid
+	return nil
+}
+
+/**
+ * Background loop refreshing the cache, on errors the previous pod
+ * list is kept
+ */
+func (client *KubernetesClient) RefreshMetadata() {
+	for {
+		if err := client.Update(); err != nil {
+			log.Println("Kubernetes metadata refresh has failed - ", fmt.Sprint(err))
+		}
+		time.Sleep(client.RefreshInterval)
+	}
+}
+
+func (client *KubernetesClient) GetContainerMetadata(containerId, podUid string) *stats.ContainerMetadata {
+	client.rwlock.RLock()
+	defer client.rwlock.RUnlock()
+
+	if metadata, present := client.containers[containerId]; present {
+		return metadata
+	}
+	// Pod level cgroups, or containers started after the last refresh
+	if metadata, present := client.pods[podUid]; present {
+		return metadata
+	}
+	return nil
+}
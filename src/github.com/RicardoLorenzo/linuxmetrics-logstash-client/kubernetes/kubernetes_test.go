@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const podsDocument = `{
+  "kind": "PodList",
+  "items": [
+    {
+      "metadata": {
+        "name": "web-0",
+        "namespace": "shop",
+        "uid": "0b1c2d3e-1111-2222-3333-444455556666",
+        "labels": {"app": "web"}
+      },
+      "status": {
+        "initContainerStatuses": [
+          {"name": "init", "image": "busybox:1", "containerID": "cri-o://aaa111"}
+        ],
+        "containerStatuses": [
+          {"name": "nginx", "image": "nginx:1.21", "containerID": "docker://bbb222"},
+          {"name": "sidecar", "image": "envoy:1", "containerID": "containerd://ccc333"},
+          {"name": "waiting", "image": "app:2", "containerID": ""}
+        ]
+      }
+    }
+  ]
+}`
+
+const emptyPodsDocument = `{"kind": "PodList", "items": []}`
+
+// Serves the documents in turn, repeating the last one
+type fakeKubelet struct {
+	documents []string
+	status    int
+	requests  int
+	token     string
+	lock      sync.Mutex
+}
+
+func (kubelet *fakeKubelet) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	kubelet.lock.Lock()
+	defer kubelet.lock.Unlock()
+
+	if request.URL.Path != "/pods" {
+		http.NotFound(writer, request)
+		return
+	}
+	kubelet.token = request.Header.Get("Authorization")
+	if kubelet.status != 0 {
+		writer.WriteHeader(kubelet.status)
+		return
+	}
+	index := kubelet.requests
+	if index >= len(kubelet.documents) {
+		index = len(kubelet.documents) - 1
+	}
+	kubelet.requests++
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write([]byte(kubelet.documents[index]))
+}
+
+func newTestClient(t *testing.T, url string) *KubernetesClient {
+	client, err := NewKubernetesClient(SourceKubelet, url+"/", "node-1", time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPodsParsing(t *testing.T) {
+	kubelet := &fakeKubelet{documents: []string{podsDocument}}
+	server := httptest.NewServer(kubelet)
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	client.Token = "secret"
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if kubelet.token != "Bearer secret" {
+		t.Errorf("Authorization header %q", kubelet.token)
+	}
+
+	expected := map[string]string{"aaa111": "init", "bbb222": "nginx", "ccc333": "sidecar"}
+	for containerId, name := range expected {
+		metadata := client.GetContainerMetadata(containerId, "")
+		if metadata == nil {
+			t.Errorf("no metadata for container %s", containerId)
+			continue
+		}
+		if metadata.Name != name || metadata.PodName != "web-0" || metadata.Namespace != "shop" ||
+			metadata.PodLabels["app"] != "web" {
+			t.Errorf("container %s has metadata %+v", containerId, metadata)
+		}
+	}
+	if len(client.containers) != len(expected) {
+		t.Errorf("%d containers cached, containers without id should be skipped", len(client.containers))
+	}
+	if metadata := client.GetContainerMetadata("bbb222", ""); metadata.Image != "nginx:1.21" {
+		t.Errorf("image %q", metadata.Image)
+	}
+}
+
+func TestPodLevelMetadata(t *testing.T) {
+	server := httptest.NewServer(&fakeKubelet{documents: []string{podsDocument}})
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	metadata := client.GetContainerMetadata("unknown", "0b1c2d3e-1111-2222-3333-444455556666")
+	if metadata == nil || metadata.PodName != "web-0" || metadata.Name != "" {
+		t.Errorf("pod metadata %+v", metadata)
+	}
+	if metadata := client.GetContainerMetadata("unknown", "unknown"); metadata != nil {
+		t.Errorf("unknown container has metadata %+v", metadata)
+	}
+}
+
+func TestGetContainerId(t *testing.T) {
+	ids := map[string]string{
+		"docker://bbb222":     "bbb222",
+		"containerd://ccc333": "ccc333",
+		"cri-o://aaa111":      "aaa111",
+		"ddd444":              "ddd444",
+	}
+	for containerID, expected := range ids {
+		if id := getContainerId(containerID); id != expected {
+			t.Errorf("getContainerId(%q) = %q, expected %q", containerID, id, expected)
+		}
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	kubelet := &fakeKubelet{documents: []string{podsDocument, emptyPodsDocument}}
+	server := httptest.NewServer(kubelet)
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if client.GetContainerMetadata("bbb222", "") == nil {
+		t.Fatal("container missing after the first refresh")
+	}
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if metadata := client.GetContainerMetadata("bbb222", ""); metadata != nil {
+		t.Errorf("deleted pod still cached %+v", metadata)
+	}
+}
+
+func TestErrorResponseKeepsCache(t *testing.T) {
+	kubelet := &fakeKubelet{documents: []string{podsDocument}}
+	server := httptest.NewServer(kubelet)
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	kubelet.lock.Lock()
+	kubelet.status = http.StatusUnauthorized
+	kubelet.lock.Unlock()
+	if err := client.Update(); err == nil {
+		t.Error("no error on a 401 response")
+	}
+	if client.GetContainerMetadata("bbb222", "") == nil {
+		t.Error("cache dropped after a failed refresh")
+	}
+}
+
+func TestCertificateVerification(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeKubelet{documents: []string{podsDocument}})
+	defer server.Close()
+
+	// Unknown authority
+	client := newTestClient(t, server.URL)
+	client.HttpClient.Transport = &http.Transport{TLSClientConfig: newTLSConfig("/nonexistent/ca.crt", false)}
+	if err := client.Update(); err == nil {
+		t.Error("unverified server certificate accepted")
+	}
+
+	// Server signed by the cluster CA
+	directory, err := ioutil.TempDir("", "kubernetes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	caPath := filepath.Join(directory, "ca.crt")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caPath, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+	client.HttpClient.Transport = &http.Transport{TLSClientConfig: newTLSConfig(caPath, false)}
+	if err := client.Update(); err != nil {
+		t.Error(err)
+	}
+
+	// Explicit opt-in
+	client.HttpClient.Transport = &http.Transport{TLSClientConfig: newTLSConfig("/nonexistent/ca.crt", true)}
+	if err := client.Update(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	config "github.com/RicardoLorenzo/linuxmetrics-logstash-client/config"
+	docker "github.com/RicardoLorenzo/linuxmetrics-logstash-client/docker"
+	kubernetes "github.com/RicardoLorenzo/linuxmetrics-logstash-client/kubernetes"
 	logstash "github.com/RicardoLorenzo/linuxmetrics-logstash-client/logstash"
 	stats "github.com/RicardoLorenzo/linuxmetrics-logstash-client/stats"
 )
@@ -27,6 +30,10 @@ var configPath string
 var logstashHost string
 var logstashPort int
 var secondsInterval int
+var printSchema bool
+var kubernetesSource string
+var kubernetesInsecure bool
+var dockerSocket string
 
 func init() {
 	if flag.Lookup("c") == nil {
@@ -44,9 +51,80 @@ func init() {
 	if flag.Lookup("proc-path") == nil {
 		flag.StringVar(&stats.ProcPath, "proc-path", "", "Linux proc path")
 	}
+	if flag.Lookup("host-root") == nil {
+		flag.StringVar(&stats.HostRootPath, "host-root", "", "Host root filesystem path")
+	}
+	if flag.Lookup("cgroup-path") == nil {
+		flag.StringVar(&stats.CgroupPath, "cgroup-path", "", "Linux cgroup hierarchy path")
+	}
+	if flag.Lookup("kubernetes") == nil {
+		flag.StringVar(&kubernetesSource, "kubernetes", "", "Kubernetes metadata source (kubelet or apiserver)")
+	}
+	if flag.Lookup("kubernetes-tls-insecure") == nil {
+		flag.BoolVar(&kubernetesInsecure, "kubernetes-tls-insecure", false, "Skip the Kubernetes server certificate verification")
+	}
+	if flag.Lookup("docker-socket") == nil {
+		flag.StringVar(&dockerSocket, "docker-socket", "", "Docker Engine API socket for container metadata")
+	}
 	if flag.Lookup("console") == nil {
 		flag.BoolVar(&logstash.ConsoleOutput, "console", false, "Console output")
 	}
+	if flag.Lookup("print-schema") == nil {
+		flag.BoolVar(&printSchema, "print-schema", false, "Print the field catalog and exit")
+	}
+}
+
+/**
+ * Prints the catalog of fields sent to Logstash as JSON. It exits with
+ * an error when the statistics structs have duplicated or missing tags.
+ */
+func printSchemaCatalog() {
+	schema, err := stats.GetSchema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	catalog, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(string(catalog))
+	os.Exit(0)
+}
+
+func forwardProcessEvents(client *logstash.LogstashClient) {
+	for {
+		client.SendEventToBacklog(<-stats.ProcessEventChannel)
+	}
+}
+
+/**
+ * Starts the background pod list refresh and registers the client as
+ * container metadata provider
+ */
+func setupKubernetesMetadata(properties *config.Config) {
+	nodeName := properties.GetProperty("kubernetes.node.name", os.Getenv("NODE_NAME"))
+	if nodeName == "" {
+		nodeName, _ = os.Hostname()
+	}
+	kubeletURL := "https://127.0.0.1:10250"
+	if nodeIP := os.Getenv("NODE_IP"); nodeIP != "" {
+		kubeletURL = "https://" + nodeIP + ":10250"
+	}
+	kubeletURL = properties.GetProperty("kubernetes.kubelet.url", kubeletURL)
+	refreshInterval := properties.GetIntProperty("kubernetes.refresh.interval", 60)
+	if !kubernetesInsecure {
+		kubernetesInsecure = properties.GetProperty("kubernetes.tls.insecure", "false") == "true"
+	}
+
+	client, err := kubernetes.NewKubernetesClient(kubernetesSource, kubeletURL, nodeName,
+		time.Duration(refreshInterval)*time.Second, kubernetesInsecure)
+	if err != nil {
+		log.Panic("Kubernetes metadata cannot be enabled - ", fmt.Sprint(err), err)
+	}
+	go client.RefreshMetadata()
+	stats.ContainerMetadataProviders = append(stats.ContainerMetadataProviders, client)
 }
 
 func main() {
@@ -56,7 +134,17 @@ func main() {
 	logstashPort = flag.Lookup("port").Value.(flag.Getter).Get().(int)
 	secondsInterval = flag.Lookup("interval").Value.(flag.Getter).Get().(int)
 	stats.ProcPath = flag.Lookup("proc-path").Value.(flag.Getter).Get().(string)
+	stats.HostRootPath = flag.Lookup("host-root").Value.(flag.Getter).Get().(string)
+	stats.CgroupPath = flag.Lookup("cgroup-path").Value.(flag.Getter).Get().(string)
 	logstash.ConsoleOutput = flag.Lookup("console").Value.(flag.Getter).Get().(bool)
+	printSchema = flag.Lookup("print-schema").Value.(flag.Getter).Get().(bool)
+	kubernetesSource = flag.Lookup("kubernetes").Value.(flag.Getter).Get().(string)
+	kubernetesInsecure = flag.Lookup("kubernetes-tls-insecure").Value.(flag.Getter).Get().(bool)
+	dockerSocket = flag.Lookup("docker-socket").Value.(flag.Getter).Get().(string)
+
+	if printSchema {
+		printSchemaCatalog()
+	}
 
 	/**
 	 * Creates a channel and waits for SIGTERM to exit application
@@ -88,10 +176,75 @@ func main() {
 		stats.ProcPath = config.GetProperty("proc.path", "/proc")
 	}
 
+	if stats.HostRootPath == "" {
+		stats.HostRootPath = config.GetProperty("host.root.path", "/")
+	}
+
+	stats.SysPath = config.GetProperty("sys.path", stats.SysPath)
+	if !strings.HasSuffix(stats.SysPath, "/") {
+		stats.SysPath = stats.SysPath + "/"
+	}
+
+	if stats.CgroupPath == "" {
+		stats.CgroupPath = config.GetProperty("cgroup.path", "/sys/fs/cgroup")
+	}
+	if !strings.HasSuffix(stats.CgroupPath, "/") {
+		stats.CgroupPath = stats.CgroupPath + "/"
+	}
+
+	stats.NetstatFields = config.GetListProperty("network.netstat.fields", stats.NetstatFields)
+	stats.TCPTopPortsCount = config.GetIntProperty("network.tcp.top_ports", stats.TCPTopPortsCount)
+	stats.FilesystemExcludeTypes = config.GetListProperty("filesystems.exclude.fstypes", stats.FilesystemExcludeTypes)
+	stats.FilesystemExcludeMountPoints = config.GetListProperty("filesystems.exclude.mountpoints",
+		stats.FilesystemExcludeMountPoints)
+	stats.DiskWholeDevicesOnly = config.GetProperty("disks.whole_devices_only", "true") == "true"
+	stats.DiskIncludeDevices = config.GetListProperty("disks.include.devices", stats.DiskIncludeDevices)
+	stats.DiskExcludeDevices = config.GetListProperty("disks.exclude.devices", stats.DiskExcludeDevices)
+	stats.PressureCgroups = config.GetListProperty("pressure.cgroups", stats.PressureCgroups)
+	stats.CgroupsEnabled = config.GetProperty("cgroups.enabled", "true") == "true"
+	stats.ProcessTopCount = config.GetIntProperty("processes.top.count", stats.ProcessTopCount)
+	stats.ProcessTopSort = config.GetProperty("processes.top.sort", stats.ProcessTopSort)
+	stats.ProcessIncludeCmdlines = config.GetListProperty("processes.include.cmdlines", stats.ProcessIncludeCmdlines)
+	stats.ProcessExcludeCmdlines = config.GetListProperty("processes.exclude.cmdlines", stats.ProcessExcludeCmdlines)
+	stats.ProcessIncludeComms = config.GetListProperty("processes.include.comms", stats.ProcessIncludeComms)
+	stats.ProcessExcludeComms = config.GetListProperty("processes.exclude.comms", stats.ProcessExcludeComms)
+	stats.ProcessIncludeUsers = config.GetListProperty("processes.include.users", stats.ProcessIncludeUsers)
+	stats.ProcessExcludeUsers = config.GetListProperty("processes.exclude.users", stats.ProcessExcludeUsers)
+	stats.ProcessIncludeCgroups = config.GetListProperty("processes.include.cgroups", stats.ProcessIncludeCgroups)
+	stats.ProcessExcludeCgroups = config.GetListProperty("processes.exclude.cgroups", stats.ProcessExcludeCgroups)
+	stats.ProcessAlwaysInclude = config.GetListProperty("processes.always_include", stats.ProcessAlwaysInclude)
+	stats.ProcessGroupBy = config.GetProperty("processes.groups.by", stats.ProcessGroupBy)
+	stats.ProcessGroupRules = config.GetListProperty("processes.groups.rules", stats.ProcessGroupRules)
+	stats.ProcessEventsEnabled = config.GetProperty("processes.events.enabled", "false") == "true"
+	stats.ProcessSmapsEnabled = config.GetProperty("processes.smaps.enabled", "false") == "true"
+	stats.ProcessSmapsInterval = config.GetIntProperty("processes.smaps.interval", stats.ProcessSmapsInterval)
+	stats.ProcessThreadsInclude = config.GetListProperty("processes.threads.include", stats.ProcessThreadsInclude)
+	stats.ProcessThreadsTopCount = config.GetIntProperty("processes.threads.top", stats.ProcessThreadsTopCount)
+	stats.ProcessSocketsEnabled = config.GetProperty("processes.sockets.enabled", "false") == "true"
+	stats.ProcessSchedstatInclude = config.GetListProperty("processes.schedstat.include", stats.ProcessSchedstatInclude)
+	stats.InterruptsTopCount = config.GetIntProperty("interrupts.top", stats.InterruptsTopCount)
+
 	if !strings.HasSuffix(stats.ProcPath, "/") {
 		stats.ProcPath = stats.ProcPath + "/"
 	}
 
+	if kubernetesSource == "" {
+		kubernetesSource = config.GetProperty("kubernetes.source", "")
+	}
+	if kubernetesSource != "" {
+		setupKubernetesMetadata(&config)
+	}
+
+	if dockerSocket == "" {
+		dockerSocket = config.GetProperty("docker.socket", "")
+	}
+	if dockerSocket != "" {
+		refreshInterval := config.GetIntProperty("docker.refresh.interval", 300)
+		dockerClient := docker.NewDockerClient(dockerSocket, time.Duration(refreshInterval)*time.Second)
+		go dockerClient.RefreshMetadata()
+		stats.ContainerMetadataProviders = append(stats.ContainerMetadataProviders, dockerClient)
+	}
+
 	logstash := logstash.NewLogstashClient(logstashHost, logstashPort, 5000)
 
 	// This background thread collects the samples from the OS
@@ -102,6 +255,10 @@ func main() {
 	 */
 	go logstash.ReadEventsFromBacklog()
 
+	if stats.ProcessEventsEnabled {
+		go forwardProcessEvents(logstash)
+	}
+
 	jsonstats := stats.NewJSONStats()
 	for {
 		eventMessage, err := jsonstats.GetStats()
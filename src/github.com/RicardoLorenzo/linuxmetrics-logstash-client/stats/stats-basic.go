@@ -5,20 +5,20 @@ import (
 )
 
 type ProcessorStats struct {
-	Cpu string `json:"cpu"`
-  User uint64 `json:"user"`
-	Nice uint64 `json:"nice"`
-	System uint64 `json:"system"`
-	IOWait uint64 `json:"iowait"`
-	PercentageUtil uint64 `json:"percentageUtil"`
+	Cpu string `json:"cpu" kind:"label"`
+  User uint64 `json:"user" kind:"counter" unit:"jiffies"`
+	Nice uint64 `json:"nice" kind:"counter" unit:"jiffies"`
+	System uint64 `json:"system" kind:"counter" unit:"jiffies"`
+	IOWait uint64 `json:"iowait" kind:"counter" unit:"jiffies"`
+	PercentageUtil uint64 `json:"percentageUtil" kind:"gauge" unit:"percent"`
 }
 
 type LinuxBasicStats struct {
 	Processors []*ProcessorStats `json:"processors"`
   AllProcessors *ProcessorStats `json:"allProcessors"`
-	Processes uint64 `json:"processes"`
-	ContextSwitches uint64 `json:"contextSwitches"`
-	Interrupts uint64 `json:"interrupts"`
+	Processes uint64 `json:"processes" kind:"counter" unit:"count"`
+	ContextSwitches uint64 `json:"contextSwitches" kind:"counter" unit:"count"`
+	Interrupts uint64 `json:"interrupts" kind:"counter" unit:"count"`
 }
 
 func (basicStats *LinuxBasicStats) getSingleCoreUsage(prev, curr StatsSample, index int) uint64 {
@@ -0,0 +1,328 @@
+package stats
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Walks the cgroup hierarchy on every sample when enabled
+var CgroupsEnabled bool = true
+
+var (
+	// Last path element of a container cgroup for docker, containerd and cri-o
+	// with both the cgroupfs and the systemd drivers
+	cgroupContainerRegExp = regexp.MustCompile(`(?:^|[-:])([0-9a-f]{64})(?:\.scope)?$`)
+	// kubepods/burstable/pod<uid> or kubepods-burstable-pod<uid with underscores>.slice
+	cgroupPodRegExp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+)
+
+/**
+ * Container ID and pod UID found in a cgroup path, either empty
+ */
+func parseCgroupPath(path string) (containerId, podUid string) {
+	if match := cgroupContainerRegExp.FindStringSubmatch(filepath.Base(path)); match != nil {
+		containerId = match[1]
+	}
+	if match := cgroupPodRegExp.FindStringSubmatch(path); match != nil {
+		podUid = strings.Replace(match[1], "_", "-", -1)
+	}
+	return containerId, podUid
+}
+
+func isCgroupV2(root string) bool {
+	_, err := os.Stat(root + "cgroup.controllers")
+	return err == nil
+}
+
+/**
+ * v1 controllers can be mounted on their own or co-mounted, the first
+ * existing directory is used
+ */
+func getCgroupV1Controller(root string, names ...string) string {
+	for _, name := range names {
+		if _, err := os.Stat(root + name); err == nil {
+			return root + name + "/"
+		}
+	}
+	return ""
+}
+
+// Single value files, "max" means no limit and is returned as zero
+func readCgroupValue(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// Flat keyed files like cpu.stat, memory.stat or memory.events
+func readCgroupKeyValues(path string) map[string]uint64 {
+	values := make(map[string]uint64)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+/**
+ * Raw accounting of a cgroup, all times in nanoseconds
+ */
+type cgroupSample struct {
+	containerId  string
+	podUid       string
+	cpuUsage     uint64
+	nrPeriods    uint64
+	nrThrottled  uint64
+	throttled    uint64
+	memoryUsage  uint64
+	memoryLimit  uint64
+	inactiveFile uint64
+	oomKills     uint64
+	readBytes    uint64
+	writeBytes   uint64
+	readIOs      uint64
+	writeIOs     uint64
+	pressure     map[string]*pressureFile
+}
+
+func (cgroup *cgroupSample) readV2(directory string) {
+	cpuStat := readCgroupKeyValues(directory + "cpu.stat")
+	cgroup.cpuUsage = cpuStat["usage_usec"] * 1000
+	cgroup.nrPeriods = cpuStat["nr_periods"]
+	cgroup.nrThrottled = cpuStat["nr_throttled"]
+	cgroup.throttled = cpuStat["throttled_usec"] * 1000
+
+	cgroup.memoryUsage, _ = readCgroupValue(directory + "memory.current")
+	cgroup.memoryLimit, _ = readCgroupValue(directory + "memory.max")
+	cgroup.inactiveFile = readCgroupKeyValues(directory + "memory.stat")["inactive_file"]
+	cgroup.oomKills = readCgroupKeyValues(directory + "memory.events")["oom_kill"]
+
+	// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+	if data, err := ioutil.ReadFile(directory + "io.stat"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			for _, field := range strings.Fields(line) {
+				tokens := strings.SplitN(field, "=", 2)
+				if len(tokens) != 2 {
+					continue
+				}
+				value, err := strconv.ParseUint(tokens[1], 10, 64)
+				if err != nil {
+					continue
+				}
+				switch tokens[0] {
+				case "rbytes":
+					cgroup.readBytes += value
+				case "wbytes":
+					cgroup.writeBytes += value
+				case "rios":
+					cgroup.readIOs += value
+				case "wios":
+					cgroup.writeIOs += value
+				}
+			}
+		}
+	}
+
+	cgroup.pressure = readPressureFiles(directory, ".pressure")
+}
+
+// 8:0 Read 1459200, the per device lines are summed and "Total" is skipped
+func (cgroup *cgroupSample) readBlkio(path string) (read, write uint64) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += value
+		case "Write":
+			write += value
+		}
+	}
+	return read, write
+}
+
+func (cgroup *cgroupSample) readV1(controllers map[string]string, path string) {
+	if directory := controllers["cpuacct"]; directory != "" {
+		cgroup.cpuUsage, _ = readCgroupValue(directory + path + "cpuacct.usage")
+	}
+	if directory := controllers["cpu"]; directory != "" {
+		cpuStat := readCgroupKeyValues(directory + path + "cpu.stat")
+		cgroup.nrPeriods = cpuStat["nr_periods"]
+		cgroup.nrThrottled = cpuStat["nr_throttled"]
+		cgroup.throttled = cpuStat["throttled_time"]
+	}
+	if directory := controllers["memory"]; directory != "" {
+		cgroup.memoryUsage, _ = readCgroupValue(directory + path + "memory.usage_in_bytes")
+		cgroup.memoryLimit, _ = readCgroupValue(directory + path + "memory.limit_in_bytes")
+		// Unlimited is reported as the largest page aligned 64 bit value
+		if cgroup.memoryLimit >= math.MaxInt64-4096 {
+			cgroup.memoryLimit = 0
+		}
+		cgroup.inactiveFile = readCgroupKeyValues(directory + path + "memory.stat")["total_inactive_file"]
+		cgroup.oomKills = readCgroupKeyValues(directory + path + "memory.oom_control")["oom_kill"]
+	}
+	if directory := controllers["blkio"]; directory != "" {
+		cgroup.readBytes, cgroup.writeBytes = cgroup.readBlkio(directory + path + "blkio.throttle.io_service_bytes_recursive")
+		cgroup.readIOs, cgroup.writeIOs = cgroup.readBlkio(directory + path + "blkio.throttle.io_serviced_recursive")
+	}
+}
+
+/**
+ * Finds the container and pod cgroups below root. Pod cgroups are the
+ * ones named after the pod UID, their children are the containers.
+ */
+func findContainerCgroups(root string) map[string]*cgroupSample {
+	cgroups := make(map[string]*cgroupSample)
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			// Cgroups are removed while walking, nothing to worry about
+			return nil
+		}
+		relative := strings.TrimPrefix(path, root)
+		containerId, podUid := parseCgroupPath(relative)
+		isPod := podUid != "" && cgroupPodRegExp.MatchString(filepath.Base(relative))
+		if containerId != "" || isPod {
+			cgroups[relative] = &cgroupSample{containerId: containerId, podUid: podUid}
+		}
+		return nil
+	})
+	return cgroups
+}
+
+func readCgroupSamples(root string) map[string]*cgroupSample {
+	if isCgroupV2(root) {
+		cgroups := findContainerCgroups(root)
+		for path, cgroup := range cgroups {
+			cgroup.readV2(root + path + "/")
+		}
+		return cgroups
+	}
+
+	controllers := map[string]string{
+		"cpu":     getCgroupV1Controller(root, "cpu", "cpu,cpuacct", "cpuacct,cpu"),
+		"cpuacct": getCgroupV1Controller(root, "cpuacct", "cpu,cpuacct", "cpuacct,cpu"),
+		"memory":  getCgroupV1Controller(root, "memory"),
+		"blkio":   getCgroupV1Controller(root, "blkio"),
+	}
+	if controllers["memory"] == "" {
+		return map[string]*cgroupSample{}
+	}
+	// Every container has a memory cgroup, its path is the same on all controllers
+	cgroups := findContainerCgroups(controllers["memory"])
+	for path, cgroup := range cgroups {
+		cgroup.readV1(controllers, path+"/")
+	}
+	return cgroups
+}
+
+type LinuxCgroupStats struct {
+	Path        string `json:"path" kind:"label"`
+	ContainerId string `json:"container_id" kind:"label"`
+	PodUid      string `json:"pod_uid" kind:"label"`
+	// 100% is one CPU fully used
+	CpuUsage              float64 `json:"cpu_usage" kind:"gauge" unit:"percent"`
+	CpuPeriods            uint64  `json:"cpu_periods" kind:"counter" unit:"count"`
+	CpuThrottledPeriods   uint64  `json:"cpu_throttled_periods" kind:"counter" unit:"count"`
+	CpuThrottledTime      float64 `json:"cpu_throttled_time" kind:"counter" unit:"ms"`
+	MemoryUsage           uint64  `json:"memory_usage" kind:"gauge" unit:"bytes"`
+	MemoryLimit           uint64  `json:"memory_limit" kind:"gauge" unit:"bytes"`
+	MemoryWorkingSet      uint64  `json:"memory_working_set" kind:"gauge" unit:"bytes"`
+	MemoryLimitPercentage float64 `json:"memory_limit_percentage" kind:"gauge" unit:"percent"`
+	OomKills              uint64  `json:"oom_kills" kind:"counter" unit:"count"`
+	IOReadBytesPerSecond  float64 `json:"io_read_bytes_per_second" kind:"counter" unit:"bytes_per_second"`
+	IOWriteBytesPerSecond float64 `json:"io_write_bytes_per_second" kind:"counter" unit:"bytes_per_second"`
+	IOReadOpsPerSecond    float64 `json:"io_read_ops_per_second" kind:"counter" unit:"per_second"`
+	IOWriteOpsPerSecond   float64 `json:"io_write_ops_per_second" kind:"counter" unit:"per_second"`
+	// cgroup v2 only
+	Pressure  *LinuxPressureStats `json:"pressure"`
+	Container *ContainerMetadata  `json:"container"`
+}
+
+func newLinuxCgroupStats(path string, prev, curr *cgroupSample, seconds float64) *LinuxCgroupStats {
+	cgroupStats := LinuxCgroupStats{}
+	cgroupStats.Path = path
+	cgroupStats.ContainerId = curr.containerId
+	cgroupStats.PodUid = curr.podUid
+	cgroupStats.Container = getContainerMetadata(curr.containerId, curr.podUid)
+
+	cgroupStats.MemoryUsage = curr.memoryUsage
+	cgroupStats.MemoryLimit = curr.memoryLimit
+	// Same as the kubelet, inactive page cache can be reclaimed
+	if curr.memoryUsage > curr.inactiveFile {
+		cgroupStats.MemoryWorkingSet = curr.memoryUsage - curr.inactiveFile
+	}
+	if curr.memoryLimit > 0 {
+		cgroupStats.MemoryLimitPercentage = 100.0 * float64(cgroupStats.MemoryWorkingSet) / float64(curr.memoryLimit)
+	}
+
+	// Counters need the same cgroup in the previous sample
+	if prev == nil {
+		cgroupStats.Pressure = newLinuxPressureStats(nil, curr.pressure, seconds)
+		return &cgroupStats
+	}
+	cgroupStats.CpuUsage = 100.0 * getCounterRate(prev.cpuUsage, curr.cpuUsage, seconds) / 1e9
+	cgroupStats.CpuPeriods = getCounterDelta(prev.nrPeriods, curr.nrPeriods)
+	cgroupStats.CpuThrottledPeriods = getCounterDelta(prev.nrThrottled, curr.nrThrottled)
+	cgroupStats.CpuThrottledTime = float64(getCounterDelta(prev.throttled, curr.throttled)) / 1e6
+	cgroupStats.OomKills = getCounterDelta(prev.oomKills, curr.oomKills)
+	cgroupStats.IOReadBytesPerSecond = getCounterRate(prev.readBytes, curr.readBytes, seconds)
+	cgroupStats.IOWriteBytesPerSecond = getCounterRate(prev.writeBytes, curr.writeBytes, seconds)
+	cgroupStats.IOReadOpsPerSecond = getCounterRate(prev.readIOs, curr.readIOs, seconds)
+	cgroupStats.IOWriteOpsPerSecond = getCounterRate(prev.writeIOs, curr.writeIOs, seconds)
+	cgroupStats.Pressure = newLinuxPressureStats(prev.pressure, curr.pressure, seconds)
+
+	return &cgroupStats
+}
+
+func NewLinuxCgroupsStats() []*LinuxCgroupStats {
+	cgroupsStats := []*LinuxCgroupStats{}
+
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+
+	for path, cgroup := range current.cgroups {
+		cgroupsStats = append(cgroupsStats, newLinuxCgroupStats(path, previous.cgroups[path], cgroup, seconds))
+	}
+	sort.Slice(cgroupsStats, func(i, j int) bool {
+		return cgroupsStats[i].Path < cgroupsStats[j].Path
+	})
+
+	return cgroupsStats
+}
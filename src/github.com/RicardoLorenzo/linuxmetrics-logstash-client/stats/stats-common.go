@@ -6,13 +6,24 @@ import (
 )
 
 type JSONStats struct {
-	Type string  `json:"type"`
-	Hostname string  `json:"hostname"`
+	Type string  `json:"type" kind:"label"`
+	Hostname string  `json:"hostname" kind:"label"`
 	BasicStats *LinuxBasicStats `json:"basic"`
 	Vmstat *LinuxVMStats `json:"vmstat"`
 	NetworkStats *LinuxNetworkStats `json:"network"`
 	Processes []*LinuxProcessStats `json:"processes"`
+	// Processes left out by the process filters and top-N selection
+	ProcessesOmitted uint64 `json:"processes_omitted" kind:"gauge" unit:"count"`
+	ProcessGroups []*LinuxProcessGroupStats `json:"process_groups"`
 	Disks []*LinuxDiskStats `json:"disks"`
+	Filesystems []*LinuxFilesystemStats `json:"filesystems"`
+	Pressure *LinuxPressureSectionStats `json:"pressure"`
+	// Sum over the CPUs of /proc/schedstat
+	Schedstat *LinuxSchedStats `json:"schedstat"`
+	Interrupts *LinuxInterruptsStats `json:"interrupts"`
+	// Usage of the system wide tables of /proc/sys
+	KernelLimits *LinuxKernelLimitsStats `json:"kernel_limits"`
+	Cgroups []*LinuxCgroupStats `json:"cgroups"`
 }
 
 func NewJSONStats() *JSONStats {
@@ -30,8 +41,16 @@ func (jsonstats *JSONStats) GetStats() (string, error) {
   jsonstats.BasicStats = NewLinuxBasicStats()
 	jsonstats.Vmstat = NewLinuxVMStats()
 	jsonstats.NetworkStats = NewLinuxNetworkStats()
-	jsonstats.Processes = NewLinuxProcessesStats()
+	processes := NewLinuxAllProcessesStats()
+	jsonstats.Processes, jsonstats.ProcessesOmitted = NewLinuxProcessesStats(processes)
+	jsonstats.ProcessGroups = NewLinuxProcessGroupsStats(processes)
 	jsonstats.Disks = NewLinuxDisksStats()
+	jsonstats.Filesystems = NewLinuxFilesystemsStats()
+	jsonstats.Pressure = NewLinuxPressureStats()
+	jsonstats.Schedstat = NewLinuxHostSchedStats()
+	jsonstats.Interrupts = NewLinuxInterruptsStats()
+	jsonstats.KernelLimits = NewLinuxKernelLimitsStats()
+	jsonstats.Cgroups = NewLinuxCgroupsStats()
 
 	response, err := json.Marshal(jsonstats)
 	if err != nil {
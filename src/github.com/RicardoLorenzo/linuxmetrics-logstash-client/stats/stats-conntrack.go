@@ -0,0 +1,142 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Connection tracking table usage, from /proc/sys/net/netfilter and
+ * /proc/net/stat/nf_conntrack. The latter has a header naming its
+ * hexadecimal columns, which change between kernel versions, then a
+ * line per possible CPU:
+ *
+ *   entries clashres found new invalid ignore delete chainlength insert insert_failed drop early_drop ...
+ *   0000002a 00000000 00000000 00000000 00000003 00000000 00000000 00000000 00000000 00000000 00000000 ...
+ */
+type conntrackSample struct {
+	count uint64
+	max   uint64
+	cpus  []map[string]uint64
+}
+
+func readConntrackStat(path string) []map[string]uint64 {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	columns := strings.Fields(lines[0])
+	cpus := []map[string]uint64{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != len(columns) {
+			continue
+		}
+		values := make(map[string]uint64)
+		for i, field := range fields {
+			values[columns[i]], _ = strconv.ParseUint(field, 16, 64)
+		}
+		cpus = append(cpus, values)
+	}
+	return cpus
+}
+
+/**
+ * Returns nil when the nf_conntrack module is not loaded, the agent
+ * then has nothing to report and should not load it
+ */
+func readConntrack(procPath string) (*conntrackSample, error) {
+	data, err := ioutil.ReadFile(procPath + "sys/net/netfilter/nf_conntrack_count")
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	conntrack := conntrackSample{}
+	conntrack.count, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	conntrack.max = readSysctlValue(procPath + "sys/net/netfilter/nf_conntrack_max")
+	conntrack.cpus = readConntrackStat(procPath + "net/stat/nf_conntrack")
+	return &conntrack, nil
+}
+
+/**
+ * Per CPU lookups and insertions in the table. insert_failed and drop
+ * count packets lost because the table was full or racing inserts
+ * clashed, early_drop entries evicted to make room for new ones.
+ */
+type ConntrackCpuStats struct {
+	Cpu               string  `json:"cpu" kind:"label"`
+	FoundRate         float64 `json:"found_rate" kind:"counter" unit:"per_second"`
+	InvalidRate       float64 `json:"invalid_rate" kind:"counter" unit:"per_second"`
+	InsertRate        float64 `json:"insert_rate" kind:"counter" unit:"per_second"`
+	InsertFailedRate  float64 `json:"insert_failed_rate" kind:"counter" unit:"per_second"`
+	DropRate          float64 `json:"drop_rate" kind:"counter" unit:"per_second"`
+	EarlyDropRate     float64 `json:"early_drop_rate" kind:"counter" unit:"per_second"`
+	SearchRestartRate float64 `json:"search_restart_rate" kind:"counter" unit:"per_second"`
+}
+
+func (cpuStats *ConntrackCpuStats) add(other *ConntrackCpuStats) {
+	cpuStats.FoundRate += other.FoundRate
+	cpuStats.InvalidRate += other.InvalidRate
+	cpuStats.InsertRate += other.InsertRate
+	cpuStats.InsertFailedRate += other.InsertFailedRate
+	cpuStats.DropRate += other.DropRate
+	cpuStats.EarlyDropRate += other.EarlyDropRate
+	cpuStats.SearchRestartRate += other.SearchRestartRate
+}
+
+/**
+ * Once count reaches max, new connections are dropped and the kernel
+ * logs "nf_conntrack: table full, dropping packet"
+ */
+type LinuxConntrackStats struct {
+	Count          uint64               `json:"count" kind:"gauge" unit:"count"`
+	Max            uint64               `json:"max" kind:"gauge" unit:"count"`
+	UsedPercentage float64              `json:"used_percentage" kind:"gauge" unit:"percent"`
+	All            *ConntrackCpuStats   `json:"all"`
+	Cpus           []*ConntrackCpuStats `json:"cpus"`
+}
+
+func NewLinuxConntrackStats() *LinuxConntrackStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+	if current.conntrack == nil {
+		return nil
+	}
+
+	conntrackStats := LinuxConntrackStats{}
+	conntrackStats.Count = current.conntrack.count
+	conntrackStats.Max = current.conntrack.max
+	conntrackStats.UsedPercentage = getLimitPercentage(current.conntrack.count, current.conntrack.max)
+
+	var prevCpus []map[string]uint64
+	if previous.conntrack != nil {
+		prevCpus = previous.conntrack.cpus
+	}
+	conntrackStats.All = &ConntrackCpuStats{Cpu: "all"}
+	for index, curr := range current.conntrack.cpus {
+		prev := curr
+		if index < len(prevCpus) {
+			prev = prevCpus[index]
+		}
+		cpuStats := ConntrackCpuStats{Cpu: "cpu" + strconv.Itoa(index)}
+		cpuStats.FoundRate = getCounterRate(prev["found"], curr["found"], seconds)
+		cpuStats.InvalidRate = getCounterRate(prev["invalid"], curr["invalid"], seconds)
+		cpuStats.InsertRate = getCounterRate(prev["insert"], curr["insert"], seconds)
+		cpuStats.InsertFailedRate = getCounterRate(prev["insert_failed"], curr["insert_failed"], seconds)
+		cpuStats.DropRate = getCounterRate(prev["drop"], curr["drop"], seconds)
+		cpuStats.EarlyDropRate = getCounterRate(prev["early_drop"], curr["early_drop"], seconds)
+		cpuStats.SearchRestartRate = getCounterRate(prev["search_restart"], curr["search_restart"], seconds)
+		conntrackStats.All.add(&cpuStats)
+		conntrackStats.Cpus = append(conntrackStats.Cpus, &cpuStats)
+	}
+	return &conntrackStats
+}
@@ -27,10 +27,38 @@ type StatsSample struct {
   cpuinfo *linuxproc.CPUInfo
   vmstat *linuxproc.VMStat
   snmp *linuxproc.Snmp
+  netstat *linuxproc.NetStat
+  // IPv4 and IPv6 sockets from /proc/net/tcp and /proc/net/tcp6
   tcpsockets []*linuxproc.NetTCPSocket
+  tcpSocketsByInode map[uint64]*linuxproc.NetSocket
+  sockstat *sockstatSample
+  // nil without the nf_conntrack module
+  conntrack *conntrackSample
   meminfo *linuxproc.MemInfo
   processes []*linuxproc.Process
+  // Pids listed but not read, for another reason than having exited
+  processReadFailed map[uint64]bool
+  processCgroups map[uint64]*processCgroup
+  processFiles map[uint64]*processFiles
+  // Pids whose /proc/<pid>/io could not be read
+  processIODenied map[uint64]bool
+  processPaths map[uint64]*processPaths
+  processMemory map[uint64]*processMemory
+  processThreads map[uint64]map[uint64]*threadSample
+  processSchedstat map[uint64]*linuxproc.ProcessSchedStat
+  schedstat *linuxproc.ProcessSchedStat
+  interrupts *interruptsFile
+  softirqs *interruptsFile
+  softnet []*softnetLine
+  kernelLimits *kernelLimitsSample
+  // User names by uid from the host /etc/passwd
+  users map[uint64]string
   diskstats []*linuxproc.DiskStat
+  diskstatsExtended map[string]*diskStatExtended
+  mounts *linuxproc.Mounts
+  pressure map[string]*pressureFile
+  cgroupPressure map[string]map[string]*pressureFile
+  cgroups map[string]*cgroupSample
 }
 
 type StatsPeriod struct {
@@ -83,13 +111,14 @@ func (statsSample *StatsSample) getProcessesIds(path string) ([]uint64, error) {
 		}
     /**
      * This is based on the assumption that user level processes are
-     * always backed by a binary file in /proc/<pid>/exe
+     * always backed by a binary file in /proc/<pid>/exe. Kernel threads
+     * have none, processes of other users without CAP_SYS_PTRACE have
+     * one that cannot be followed, as do kernel threads then, which are
+     * told apart by their stat flags.
      */
      var path string = statsSample.getProcessPath(pid, "exe")
-     if _, err := os.Stat(path); err == nil {
-       if !os.IsNotExist(err) {
-         pids = append(pids, uint64(pid))
-       }
+     if _, err := os.Stat(path); err == nil || os.IsPermission(err) {
+       pids = append(pids, uint64(pid))
      }
 	}
 
@@ -115,6 +144,11 @@ func NewStatsSample() (statsSample StatsSample, err error) {
   	return statsSample, err
   }
 
+  statsSample.netstat, err = linuxproc.ReadNetStat(ProcPath + "net/netstat")
+  if err != nil {
+  	return statsSample, err
+  }
+
   sockets, err := linuxproc.ReadNetTCPSockets(ProcPath + "net/tcp", linuxproc.NetIPv4Decoder)
   if err != nil {
   	return statsSample, err
@@ -125,6 +159,27 @@ func NewStatsSample() (statsSample StatsSample, err error) {
     statsSample.tcpsockets = append(statsSample.tcpsockets, &sockets.Sockets[i])
   }
 
+  // IPv6 may be disabled on the host, in which case there is no tcp6 file
+  sockets, err = linuxproc.ReadNetTCPSockets(ProcPath + "net/tcp6", linuxproc.NetIPv6Decoder)
+  if err == nil {
+    for i, _ := range sockets.Sockets {
+      statsSample.tcpsockets = append(statsSample.tcpsockets, &sockets.Sockets[i])
+    }
+  } else if !os.IsNotExist(err) {
+  	return statsSample, err
+  }
+  if ProcessSocketsEnabled {
+    statsSample.tcpSocketsByInode = getTCPSocketsByInode(statsSample.tcpsockets)
+  }
+
+  // Restricted network namespaces may deny these, only their section is lost
+  if statsSample.sockstat, err = readSockstat(ProcPath); err != nil {
+    log.Println("Cannot read socket statistics -", err)
+  }
+  if statsSample.conntrack, err = readConntrack(ProcPath); err != nil {
+    log.Println("Cannot read connection tracking statistics -", err)
+  }
+
   disks, err := linuxproc.ReadDiskStats(ProcPath + "diskstats")
   if err != nil {
   	return statsSample, err
@@ -133,6 +188,36 @@ func NewStatsSample() (statsSample StatsSample, err error) {
   for i, _ := range disks {
     statsSample.diskstats = append(statsSample.diskstats, &disks[i])
   }
+  statsSample.diskstatsExtended, err = readDiskStatsExtended(ProcPath + "diskstats")
+  if err != nil {
+  	return statsSample, err
+  }
+
+  statsSample.pressure = readPressureFiles(ProcPath + "pressure/", "")
+  statsSample.cgroupPressure = make(map[string]map[string]*pressureFile)
+  for _, cgroup := range PressureCgroups {
+    cgroup = strings.Trim(cgroup, "/")
+    statsSample.cgroupPressure[cgroup] = readPressureFiles(CgroupPath + cgroup + "/", ".pressure")
+  }
+
+  if CgroupsEnabled {
+    statsSample.cgroups = readCgroupSamples(CgroupPath)
+  }
+
+  /**
+   * The mount table of init is the one of the host, even when this
+   * agent runs in a container with the host /proc mounted
+   */
+  statsSample.mounts, err = linuxproc.ReadMounts(ProcPath + "1/mounts")
+  if err != nil {
+    log.Println("Cannot read the host mount table -", err)
+  }
+
+  statsSample.schedstat = readHostSchedstat(ProcPath + "schedstat")
+  statsSample.interrupts = readInterruptsFile(ProcPath + "interrupts")
+  statsSample.softirqs = readInterruptsFile(ProcPath + "softirqs")
+  statsSample.softnet = readSoftnetStat(ProcPath + "net/softnet_stat")
+  statsSample.kernelLimits = readKernelLimits(ProcPath)
 
   /**
    * Getting list of all user-level processes
@@ -142,12 +227,50 @@ func NewStatsSample() (statsSample StatsSample, err error) {
   	return statsSample, err
   }
 
+  statsSample.processReadFailed = make(map[uint64]bool)
+  statsSample.processCgroups = make(map[uint64]*processCgroup)
+  statsSample.processFiles = make(map[uint64]*processFiles)
+  statsSample.processIODenied = make(map[uint64]bool)
+  statsSample.processPaths = make(map[uint64]*processPaths)
+  statsSample.processThreads = make(map[uint64]map[uint64]*threadSample)
+  statsSample.processSchedstat = make(map[uint64]*linuxproc.ProcessSchedStat)
+  statsSample.users = readUserNames()
   for _, pid := range processesIds {
-    process, err := linuxproc.ReadProcess(pid, ProcPath)
+    process, ioReadable, err := readProcess(statsSample.getProcessPath(pid, ""))
     if err != nil {
-    	return statsSample, err
+      // Exited since the directory was listed, or denied while still running
+      if !os.IsNotExist(err) {
+        statsSample.processReadFailed[pid] = true
+      }
+      continue
+    }
+    if process.Stat.Flags & pfKthread != 0 {
+      continue
+    }
+    if !ioReadable {
+      statsSample.processIODenied[pid] = true
     }
     statsSample.processes = append(statsSample.processes, process)
+
+    // The process may have exited meanwhile, it is then reported without cgroup
+    if cgroup, err := readProcessCgroup(statsSample.getProcessPath(pid, "cgroup")); err == nil {
+      statsSample.processCgroups[pid] = cgroup
+    }
+    if files := readProcessFiles(statsSample.getProcessPath(pid, "")); files != nil {
+      statsSample.processFiles[pid] = files
+    }
+    statsSample.processPaths[pid] = readProcessPaths(statsSample.getProcessPath(pid, ""))
+    if isThreadsProcess(process) {
+      statsSample.processThreads[pid] = readProcessThreads(statsSample.getProcessPath(pid, ""))
+    }
+    if isSchedstatProcess(process) {
+      if schedstat, err := linuxproc.ReadProcessSchedStat(statsSample.getProcessPath(pid, "schedstat")); err == nil {
+        statsSample.processSchedstat[pid] = schedstat
+      }
+    }
+  }
+  if ProcessSmapsEnabled {
+    statsSample.processMemory = readProcessesMemory(statsSample.processes)
   }
 
   statsSample.time = uint64(time.Now().UnixNano()) / uint64(time.Millisecond)
@@ -162,6 +285,36 @@ func (statsSample *StatsSample) getSystemCPUCount() uint64 {
   return uint64(len(statsSample.cpuinfo.Processors))
 }
 
+/**
+ * Elapsed time between two samples in seconds, used to turn counter
+ * deltas into per second rates
+ */
+func (statsSample *StatsSample) getElapsedSeconds(previous StatsSample) float64 {
+  if statsSample.time <= previous.time {
+    return 0
+  }
+  return float64(statsSample.time - previous.time) / 1000.0
+}
+
+/**
+ * Per second rate of a monotonic counter. Counter wraps and resets
+ * are reported as zero instead of a huge unsigned delta.
+ */
+func getCounterRate(previous, current uint64, seconds float64) float64 {
+  if current < previous || seconds <= 0 {
+    return 0
+  }
+  return float64(current - previous) / seconds
+}
+
+// Delta of a monotonic counter, zero on wraps and resets like getCounterRate
+func getCounterDelta(previous, current uint64) uint64 {
+  if current < previous {
+    return 0
+  }
+  return current - previous
+}
+
 func (statsSample *StatsSample) clone() StatsSample {
     clone := *statsSample
     return clone
@@ -205,12 +358,23 @@ func (statsPeriod *StatsPeriod) WaitForSamples() {
 }
 
 func CollectStatsSamples(secondsInterval time.Duration) {
+  var lastSample StatsSample
   for {
     statsSample, err := NewStatsSample()
     if err != nil {
       log.Println(fmt.Sprint(err), err)
     }
     SharedStatsPeriod.AddStatsSample(statsSample)
+    /**
+     * Lifecycle events are diffed here, once per pair of complete
+     * samples, whatever the pace of the documents sent to Logstash
+     */
+    if err == nil {
+      if ProcessEventsEnabled {
+        sendProcessEvents(lastSample, statsSample)
+      }
+      lastSample = statsSample
+    }
     if SharedStatsPeriod.HasPreviousSamples() {
       /**
        * Non-blocking approach.
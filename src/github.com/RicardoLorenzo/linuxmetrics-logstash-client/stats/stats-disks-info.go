@@ -0,0 +1,201 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+var (
+	// Linux sysfs path, block devices are not namespaced so the container one is fine
+	SysPath string = "/sys/"
+	// Partitions are skipped unless disabled
+	DiskWholeDevicesOnly bool = true
+	// Regular expressions on the kernel device name, an empty include list means all
+	DiskIncludeDevices = []string{}
+	DiskExcludeDevices = []string{
+		"^(loop|ram|zram|fd|sr|nbd)[0-9]+$",
+	}
+	diskIncludeDevices = newPatternList(&DiskIncludeDevices)
+	diskExcludeDevices = newPatternList(&DiskExcludeDevices)
+
+	// Information of the reported disks by name and device number
+	diskInfoCache = make(map[string]*diskInfo)
+	diskInfoLock  sync.Mutex
+)
+
+/**
+ * Static information about a block device from sysfs and the host /dev
+ */
+type diskInfo struct {
+	Alias      string
+	DeviceId   string
+	Model      string
+	Rotational bool
+	SizeBytes  uint64
+	Scheduler  string
+	ParentDisk string
+}
+
+func (info *diskInfo) readSysFile(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// /proc/diskstats uses "cciss/c0d0" where sysfs uses "cciss!c0d0"
+func getSysBlockName(name string) string {
+	return strings.Replace(name, "/", "!", -1)
+}
+
+func isWholeDisk(name string) bool {
+	if _, err := os.Stat(SysPath + "block"); err != nil {
+		// Without sysfs partitions cannot be told apart
+		return true
+	}
+	_, err := os.Stat(SysPath + "block/" + getSysBlockName(name))
+	return err == nil
+}
+
+func isDiskSelected(name string) bool {
+	if diskExcludeDevices.matches(name) {
+		return false
+	}
+	if !diskIncludeDevices.isEmpty() && !diskIncludeDevices.matches(name) {
+		return false
+	}
+	return !DiskWholeDevicesOnly || isWholeDisk(name)
+}
+
+/**
+ * Symbolic links in a directory of the host /dev pointing to the
+ * device, like /dev/disk/by-id/ata-XXX -> ../../sda
+ */
+func (info *diskInfo) getDeviceLinks(directory, name string) []string {
+	var links []string
+
+	entries, err := ioutil.ReadDir(filepath.Join(HostRootPath, directory))
+	if err != nil {
+		return links
+	}
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(HostRootPath, directory, entry.Name()))
+		if err == nil && filepath.Base(target) == name {
+			links = append(links, entry.Name())
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+func (info *diskInfo) readAlias(sysName, name string) {
+	// Device mapper: LVM volumes, dm-crypt, multipath
+	if alias := info.readSysFile(SysPath + "block/" + sysName + "/dm/name"); alias != "" {
+		info.Alias = alias
+		return
+	}
+	if links := info.getDeviceLinks("dev/mapper", name); len(links) > 0 {
+		info.Alias = links[0]
+		return
+	}
+	// Software RAID arrays created with a name
+	if links := info.getDeviceLinks("dev/md", name); len(links) > 0 {
+		info.Alias = links[0]
+	}
+}
+
+func (info *diskInfo) readDeviceId(name string) {
+	// World wide names are stable but meaningless to people, used as last resort
+	links := info.getDeviceLinks("dev/disk/by-id", name)
+	for _, link := range links {
+		if !strings.HasPrefix(link, "wwn-") {
+			info.DeviceId = link
+			return
+		}
+	}
+	if len(links) > 0 {
+		info.DeviceId = links[0]
+	}
+}
+
+func (info *diskInfo) readScheduler(path string) {
+	// "none [mq-deadline] kyber bfq", the active one is in brackets
+	scheduler := info.readSysFile(path)
+	if start := strings.Index(scheduler, "["); start >= 0 {
+		if end := strings.Index(scheduler[start:], "]"); end > 0 {
+			scheduler = scheduler[start+1 : start+end]
+		}
+	}
+	info.Scheduler = scheduler
+}
+
+func newDiskInfo(name string) *diskInfo {
+	info := diskInfo{}
+	sysName := getSysBlockName(name)
+	diskPath := SysPath + "block/" + sysName
+
+	// Partitions live under the directory of their parent disk
+	if _, err := os.Stat(SysPath + "class/block/" + sysName + "/partition"); err == nil {
+		if devicePath, err := filepath.EvalSymlinks(SysPath + "class/block/" + sysName); err == nil {
+			info.ParentDisk = filepath.Base(filepath.Dir(devicePath))
+			diskPath = SysPath + "block/" + info.ParentDisk
+		}
+	}
+
+	if size, err := strconv.ParseUint(info.readSysFile(SysPath+"class/block/"+sysName+"/size"), 10, 64); err == nil {
+		info.SizeBytes = size * sectorSize
+	}
+	info.Model = info.readSysFile(diskPath + "/device/model")
+	info.Rotational = info.readSysFile(diskPath+"/queue/rotational") == "1"
+	info.readScheduler(diskPath + "/queue/scheduler")
+	info.readAlias(sysName, name)
+	info.readDeviceId(name)
+
+	return &info
+}
+
+func getDiskInfoKey(disk *linuxproc.DiskStat) string {
+	return disk.Name + " " + strconv.Itoa(disk.Major) + ":" + strconv.Itoa(disk.Minor)
+}
+
+/**
+ * Information of the given disks by name. Reading it walks the host /dev
+ * directories, so it is only read again when a disk is added, removed or
+ * recreated with another device number, which is also when aliases and
+ * device ids may change.
+ */
+func getDisksInfo(disks []*linuxproc.DiskStat) map[string]*diskInfo {
+	diskInfoLock.Lock()
+	defer diskInfoLock.Unlock()
+
+	changed := len(disks) != len(diskInfoCache)
+	for _, disk := range disks {
+		if _, present := diskInfoCache[getDiskInfoKey(disk)]; !present {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		diskInfoCache = make(map[string]*diskInfo)
+		for _, disk := range disks {
+			diskInfoCache[getDiskInfoKey(disk)] = newDiskInfo(disk.Name)
+		}
+	}
+
+	disksInfo := make(map[string]*diskInfo)
+	for _, disk := range disks {
+		disksInfo[disk.Name] = diskInfoCache[getDiskInfoKey(disk)]
+	}
+	return disksInfo
+}
@@ -1,7 +1,12 @@
 package stats
 
 import (
+	"io/ioutil"
 	"math"
+	"strconv"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
 )
 
 /*
@@ -37,39 +42,188 @@ const MEGABYTE float64 = 1024 * 1024
  * https://www.kernel.org/doc/Documentation/iostats.txt
  */
 type LinuxDiskStats struct {
-	Name string `json:"name"`
-	ReadIOs uint64 `json:"read_io"`
-	WriteIOs uint64 `json:"write_io"`
-	ReadMerges uint64 `json:"read_io_merged"`
-	WriteMerges uint64 `json:"write_io_merged"`
-	IOTicks uint64 `json:"io_ticks"`
-	QueueSize uint64 `json:"queue_size"`
-	TimeInQueue uint64 `json:"time_in_queue"`
-	ReadMBPerSecond uint64 `json:"read_mbps"`
-	WriteMBPerSecond uint64 `json:"write_mbps"`
+	Name string `json:"name" kind:"label"`
+	// Device mapper or md name, like "vg0-root" for dm-3
+	Alias string `json:"alias" kind:"label"`
+	DeviceId string `json:"device_id" kind:"label"`
+	Model string `json:"model" kind:"label"`
+	Rotational bool `json:"rotational" kind:"label"`
+	SizeBytes uint64 `json:"size_bytes" kind:"gauge" unit:"bytes"`
+	Scheduler string `json:"scheduler" kind:"label"`
+	ParentDisk string `json:"parent_disk" kind:"label"`
+	ReadIOs uint64 `json:"read_io" kind:"counter" unit:"count"`
+	WriteIOs uint64 `json:"write_io" kind:"counter" unit:"count"`
+	ReadMerges uint64 `json:"read_io_merged" kind:"counter" unit:"count"`
+	WriteMerges uint64 `json:"write_io_merged" kind:"counter" unit:"count"`
+	IOTicks uint64 `json:"io_ticks" kind:"counter" unit:"ms"`
+	QueueSize uint64 `json:"queue_size" kind:"gauge" unit:"count"`
+	TimeInQueue uint64 `json:"time_in_queue" kind:"counter" unit:"ms"`
+	ReadMBPerSecond uint64 `json:"read_mbps" kind:"counter" unit:"megabytes"`
+	WriteMBPerSecond uint64 `json:"write_mbps" kind:"counter" unit:"megabytes"`
+	/**
+	 * iostat -x equivalents computed over the sample interval
+	 */
+	ReadIOPS float64 `json:"read_iops" kind:"counter" unit:"per_second"`
+	WriteIOPS float64 `json:"write_iops" kind:"counter" unit:"per_second"`
+	ReadBytesPerSecond float64 `json:"read_bytes_per_second" kind:"counter" unit:"bytes_per_second"`
+	WriteBytesPerSecond float64 `json:"write_bytes_per_second" kind:"counter" unit:"bytes_per_second"`
+	ReadAwait float64 `json:"read_await" kind:"gauge" unit:"ms"`
+	WriteAwait float64 `json:"write_await" kind:"gauge" unit:"ms"`
+	Await float64 `json:"await" kind:"gauge" unit:"ms"`
+	ReadAvgRequestSize float64 `json:"read_avg_request_size" kind:"gauge" unit:"bytes"`
+	WriteAvgRequestSize float64 `json:"write_avg_request_size" kind:"gauge" unit:"bytes"`
+	AvgQueueLength float64 `json:"avg_queue_length" kind:"gauge" unit:"count"`
+	Utilization float64 `json:"utilization" kind:"gauge" unit:"percent"`
+	// Kernel 4.18+
+	DiscardIOs uint64 `json:"discard_io" kind:"counter" unit:"count"`
+	DiscardMerges uint64 `json:"discard_io_merged" kind:"counter" unit:"count"`
+	DiscardBytes uint64 `json:"discard_bytes" kind:"counter" unit:"bytes"`
+	DiscardAwait float64 `json:"discard_await" kind:"gauge" unit:"ms"`
+	// Kernel 5.5+
+	FlushIOs uint64 `json:"flush_io" kind:"counter" unit:"count"`
+	FlushAwait float64 `json:"flush_await" kind:"gauge" unit:"ms"`
+}
+
+/**
+ * Fields 15 to 20 of /proc/diskstats, not parsed by linuxproc.DiskStat
+ *
+ * https://www.kernel.org/doc/Documentation/ABI/testing/procfs-diskstats
+ */
+type diskStatExtended struct {
+	DiscardIOs uint64
+	DiscardMerges uint64
+	DiscardSectors uint64
+	DiscardTicks uint64
+	FlushIOs uint64
+	FlushTicks uint64
+}
+
+func readDiskStatsExtended(path string) (map[string]*diskStatExtended, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make(map[string]*diskStatExtended)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 18 {
+			continue
+		}
+		disk := diskStatExtended{}
+		disk.DiscardIOs, _ = strconv.ParseUint(fields[14], 10, 64)
+		disk.DiscardMerges, _ = strconv.ParseUint(fields[15], 10, 64)
+		disk.DiscardSectors, _ = strconv.ParseUint(fields[16], 10, 64)
+		disk.DiscardTicks, _ = strconv.ParseUint(fields[17], 10, 64)
+		if len(fields) >= 20 {
+			disk.FlushIOs, _ = strconv.ParseUint(fields[18], 10, 64)
+			disk.FlushTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+		}
+		disks[fields[2]] = &disk
+	}
+	return disks, nil
 }
 
 func (disksStats *LinuxDiskStats) getMBPerSecond(sectors uint64) uint64 {
 	return uint64(math.Floor(float64(sectors * sectorSize) / MEGABYTE))
 }
 
+// Average milliseconds per request, zero when there were no requests
+func (diskStats *LinuxDiskStats) getAverage(total, requests uint64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(total) / float64(requests)
+}
+
+func (diskStats *LinuxDiskStats) setDiskInfo(info *diskInfo) {
+	diskStats.Alias = info.Alias
+	diskStats.DeviceId = info.DeviceId
+	diskStats.Model = info.Model
+	diskStats.Rotational = info.Rotational
+	diskStats.SizeBytes = info.SizeBytes
+	diskStats.Scheduler = info.Scheduler
+	diskStats.ParentDisk = info.ParentDisk
+}
+
+func (diskStats *LinuxDiskStats) setExtendedStats(prev, curr *diskStatExtended) {
+	if prev == nil || curr == nil {
+		return
+	}
+	diskStats.DiscardIOs = getCounterDelta(prev.DiscardIOs, curr.DiscardIOs)
+	diskStats.DiscardMerges = getCounterDelta(prev.DiscardMerges, curr.DiscardMerges)
+	diskStats.DiscardBytes = getCounterDelta(prev.DiscardSectors, curr.DiscardSectors) * sectorSize
+	diskStats.DiscardAwait = diskStats.getAverage(getCounterDelta(prev.DiscardTicks, curr.DiscardTicks), diskStats.DiscardIOs)
+	diskStats.FlushIOs = getCounterDelta(prev.FlushIOs, curr.FlushIOs)
+	diskStats.FlushAwait = diskStats.getAverage(getCounterDelta(prev.FlushTicks, curr.FlushTicks), diskStats.FlushIOs)
+}
+
+func (diskStats *LinuxDiskStats) setIntervalStats(prev, curr *linuxproc.DiskStat, seconds float64) {
+	readTicks := getCounterDelta(prev.ReadTicks, curr.ReadTicks)
+	writeTicks := getCounterDelta(prev.WriteTicks, curr.WriteTicks)
+	readBytes := getCounterDelta(prev.ReadSectors, curr.ReadSectors) * sectorSize
+	writeBytes := getCounterDelta(prev.WriteSectors, curr.WriteSectors) * sectorSize
+
+	diskStats.ReadAwait = diskStats.getAverage(readTicks, diskStats.ReadIOs)
+	diskStats.WriteAwait = diskStats.getAverage(writeTicks, diskStats.WriteIOs)
+	diskStats.Await = diskStats.getAverage(readTicks + writeTicks, diskStats.ReadIOs + diskStats.WriteIOs)
+	diskStats.ReadAvgRequestSize = diskStats.getAverage(readBytes, diskStats.ReadIOs)
+	diskStats.WriteAvgRequestSize = diskStats.getAverage(writeBytes, diskStats.WriteIOs)
+
+	if seconds <= 0 {
+		return
+	}
+	diskStats.ReadIOPS = float64(diskStats.ReadIOs) / seconds
+	diskStats.WriteIOPS = float64(diskStats.WriteIOs) / seconds
+	diskStats.ReadBytesPerSecond = float64(readBytes) / seconds
+	diskStats.WriteBytesPerSecond = float64(writeBytes) / seconds
+	// io_ticks and time_in_queue are milliseconds
+	diskStats.AvgQueueLength = float64(diskStats.TimeInQueue) / (seconds * 1000.0)
+	diskStats.Utilization = math.Min(100.0, 100.0 * float64(diskStats.IOTicks) / (seconds * 1000.0))
+}
+
 func NewLinuxDisksStats() []*LinuxDiskStats {
 	disksStats := []*LinuxDiskStats{}
 
 	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+
+	// Devices can be added or removed between samples
+	previousDisks := make(map[string]*linuxproc.DiskStat)
+	for i, _ := range previous.diskstats {
+		previousDisks[previous.diskstats[i].Name] = previous.diskstats[i]
+	}
+
+	selectedDisks := []*linuxproc.DiskStat{}
+	for _, disk := range current.diskstats {
+		if isDiskSelected(disk.Name) {
+			selectedDisks = append(selectedDisks, disk)
+		}
+	}
+	disksInfo := getDisksInfo(selectedDisks)
+
+  // Counters wrap on 32-bit kernels and restart with a recreated device, such
+  // intervals report 0
+	for _, curr := range selectedDisks {
+		prev, present := previousDisks[curr.Name]
+		if !present {
+			continue
+		}
 
-  // TODO In a very busy systems this counters may wrap. This is not curently expected
-	for i, _ := range current.diskstats {
 		diskStats := LinuxDiskStats{}
-		diskStats.Name = current.diskstats[i].Name
-		diskStats.ReadIOs = current.diskstats[i].ReadIOs - previous.diskstats[i].ReadIOs
-		diskStats.WriteIOs = current.diskstats[i].WriteIOs - previous.diskstats[i].WriteIOs
-		diskStats.ReadMerges = current.diskstats[i].ReadMerges - previous.diskstats[i].ReadMerges
-		diskStats.WriteMerges = current.diskstats[i].WriteMerges - previous.diskstats[i].WriteMerges
-		diskStats.QueueSize = current.diskstats[i].InFlight
-		diskStats.TimeInQueue = current.diskstats[i].TimeInQueue - previous.diskstats[i].TimeInQueue
-		diskStats.ReadMBPerSecond = diskStats.getMBPerSecond(current.diskstats[i].ReadSectors - previous.diskstats[i].ReadSectors)
-		diskStats.WriteMBPerSecond = diskStats.getMBPerSecond(current.diskstats[i].WriteSectors - previous.diskstats[i].WriteSectors)
+		diskStats.Name = curr.Name
+		diskStats.setDiskInfo(disksInfo[curr.Name])
+		diskStats.ReadIOs = getCounterDelta(prev.ReadIOs, curr.ReadIOs)
+		diskStats.WriteIOs = getCounterDelta(prev.WriteIOs, curr.WriteIOs)
+		diskStats.ReadMerges = getCounterDelta(prev.ReadMerges, curr.ReadMerges)
+		diskStats.WriteMerges = getCounterDelta(prev.WriteMerges, curr.WriteMerges)
+		diskStats.IOTicks = getCounterDelta(prev.IOTicks, curr.IOTicks)
+		diskStats.QueueSize = curr.InFlight
+		diskStats.TimeInQueue = getCounterDelta(prev.TimeInQueue, curr.TimeInQueue)
+		diskStats.ReadMBPerSecond = diskStats.getMBPerSecond(getCounterDelta(prev.ReadSectors, curr.ReadSectors))
+		diskStats.WriteMBPerSecond = diskStats.getMBPerSecond(getCounterDelta(prev.WriteSectors, curr.WriteSectors))
+		diskStats.setIntervalStats(prev, curr, seconds)
+		diskStats.setExtendedStats(previous.diskstatsExtended[curr.Name], current.diskstatsExtended[curr.Name])
 		disksStats = append(disksStats, &diskStats)
 	}
 
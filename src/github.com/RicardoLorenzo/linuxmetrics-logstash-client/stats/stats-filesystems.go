@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	// Prefix where the host root filesystem is mounted, "/" when not containerized
+	HostRootPath string = "/"
+	// Pseudo and in-memory filesystems that never fill a disk
+	FilesystemExcludeTypes = []string{
+		"autofs", "binfmt_misc", "bpf", "cgroup", "cgroup2", "configfs",
+		"debugfs", "devpts", "devtmpfs", "fuse.lxcfs", "fusectl", "hugetlbfs",
+		"mqueue", "nsfs", "overlay", "proc", "pstore", "ramfs", "rpc_pipefs",
+		"securityfs", "selinuxfs", "squashfs", "sysfs", "tmpfs", "tracefs",
+	}
+	// Regular expressions on the mount point
+	FilesystemExcludeMountPoints = []string{
+		"^/(dev|proc|sys|run)($|/)",
+		"^/var/lib/(docker|containerd|kubelet)/",
+	}
+	filesystemExcludeMountPoints = newPatternList(&FilesystemExcludeMountPoints)
+)
+
+type LinuxFilesystemStats struct {
+	Device               string  `json:"device" kind:"label"`
+	MountPoint           string  `json:"mountpoint" kind:"label"`
+	FSType               string  `json:"fstype" kind:"label"`
+	SizeBytes            uint64  `json:"size_bytes" kind:"gauge" unit:"bytes"`
+	UsedBytes            uint64  `json:"used_bytes" kind:"gauge" unit:"bytes"`
+	AvailableBytes       uint64  `json:"available_bytes" kind:"gauge" unit:"bytes"`
+	UsedPercentage       float64 `json:"used_percentage" kind:"gauge" unit:"percent"`
+	Inodes               uint64  `json:"inodes" kind:"gauge" unit:"count"`
+	InodesUsed           uint64  `json:"inodes_used" kind:"gauge" unit:"count"`
+	InodesFree           uint64  `json:"inodes_free" kind:"gauge" unit:"count"`
+	InodesUsedPercentage float64 `json:"inodes_used_percentage" kind:"gauge" unit:"percent"`
+}
+
+/**
+ * Mount points in /proc/<pid>/mounts escape blanks and backslashes as
+ * octal sequences, "/mnt/my\040disk" is "/mnt/my disk"
+ */
+func (filesystemStats *LinuxFilesystemStats) unescapeMountPoint(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if code, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+	return unescaped.String()
+}
+
+func (filesystemStats *LinuxFilesystemStats) getPercentage(used, available uint64) float64 {
+	// Same as df, reserved blocks are not part of the capacity
+	if used+available == 0 {
+		return 0
+	}
+	return 100.0 * float64(used) / float64(used+available)
+}
+
+func (filesystemStats *LinuxFilesystemStats) readStatfs(path string) error {
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return err
+	}
+	blockSize := uint64(fs.Bsize)
+
+	filesystemStats.SizeBytes = fs.Blocks * blockSize
+	filesystemStats.UsedBytes = (fs.Blocks - fs.Bfree) * blockSize
+	filesystemStats.AvailableBytes = fs.Bavail * blockSize
+	filesystemStats.UsedPercentage = filesystemStats.getPercentage(filesystemStats.UsedBytes,
+		filesystemStats.AvailableBytes)
+
+	filesystemStats.Inodes = fs.Files
+	filesystemStats.InodesFree = fs.Ffree
+	filesystemStats.InodesUsed = fs.Files - fs.Ffree
+	filesystemStats.InodesUsedPercentage = filesystemStats.getPercentage(filesystemStats.InodesUsed,
+		filesystemStats.InodesFree)
+	return nil
+}
+
+func NewLinuxFilesystemsStats() []*LinuxFilesystemStats {
+	filesystemsStats := []*LinuxFilesystemStats{}
+
+	_, current := SharedStatsPeriod.GetStatsSamples()
+	if current.mounts == nil {
+		return filesystemsStats
+	}
+
+	// Bind mounts show the same device more than once
+	devices := make(map[string]bool)
+
+	for _, mount := range current.mounts.Mounts {
+		filesystemStats := LinuxFilesystemStats{}
+		filesystemStats.Device = mount.Device
+		filesystemStats.MountPoint = filesystemStats.unescapeMountPoint(mount.MountPoint)
+		filesystemStats.FSType = mount.FSType
+
+		if containsString(FilesystemExcludeTypes, mount.FSType) ||
+			filesystemExcludeMountPoints.matches(filesystemStats.MountPoint) ||
+			devices[mount.Device] {
+			continue
+		}
+
+		err := filesystemStats.readStatfs(filepath.Join(HostRootPath, filesystemStats.MountPoint))
+		if err != nil {
+			log.Println("Cannot read filesystem", filesystemStats.MountPoint, "-", err)
+			continue
+		}
+		if filesystemStats.SizeBytes == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(mount.Device, "/") {
+			devices[mount.Device] = true
+		}
+		filesystemsStats = append(filesystemsStats, &filesystemStats)
+	}
+
+	return filesystemsStats
+}
@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"log"
+	"regexp"
+	"sync"
+)
+
+/**
+ * Regular expressions read from the configuration. They are compiled
+ * the first time they are used, invalid expressions are logged and
+ * ignored rather than stopping the collection.
+ */
+type patternList struct {
+	patterns *[]string
+	compiled []*regexp.Regexp
+	once     sync.Once
+}
+
+func newPatternList(patterns *[]string) *patternList {
+	return &patternList{patterns: patterns}
+}
+
+func (list *patternList) compile() {
+	for _, pattern := range *list.patterns {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Println("Ignoring invalid pattern", pattern, "-", err)
+			continue
+		}
+		list.compiled = append(list.compiled, expression)
+	}
+}
+
+func (list *patternList) isEmpty() bool {
+	list.once.Do(list.compile)
+	return len(list.compiled) == 0
+}
+
+func (list *patternList) matches(value string) bool {
+	list.once.Do(list.compile)
+	for _, expression := range list.compiled {
+		if expression.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
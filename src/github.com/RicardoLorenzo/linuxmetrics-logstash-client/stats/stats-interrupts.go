@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IRQ lines reported, by total rate
+var InterruptsTopCount int = 10
+
+type interruptLine struct {
+	name        string
+	description string
+	// Per CPU, in the order of the file header
+	counts []uint64
+}
+
+/**
+ * /proc/interrupts and /proc/softirqs, a header with the online CPUs and
+ * one line per source:
+ *
+ *              CPU0       CPU1
+ *    24:          1          0  IO-APIC   5-edge      ACPI:Ged
+ *   NMI:          0          0  Non-maskable interrupts
+ *   ERR:          0
+ *   NET_RX:    4409       5120
+ *
+ * linuxproc.ReadInterrupts parses the same format but drops the header,
+ * and with an offline CPU the header is the only place telling that the
+ * columns are CPU0 and CPU2. It also fails the whole file on a single
+ * count that does not parse.
+ */
+type interruptsFile struct {
+	cpus  []string
+	lines map[string]*interruptLine
+}
+
+func readInterruptsFile(path string) *interruptsFile {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	rows := strings.Split(string(data), "\n")
+	if len(rows) == 0 {
+		return nil
+	}
+
+	file := interruptsFile{}
+	file.lines = make(map[string]*interruptLine)
+	for _, cpu := range strings.Fields(rows[0]) {
+		file.cpus = append(file.cpus, strings.ToLower(cpu))
+	}
+	for _, row := range rows[1:] {
+		fields := strings.Fields(row)
+		if len(fields) == 0 {
+			continue
+		}
+		line := interruptLine{name: strings.TrimSuffix(fields[0], ":")}
+		i := 1
+		for ; i < len(fields) && i <= len(file.cpus); i++ {
+			count, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				break
+			}
+			line.counts = append(line.counts, count)
+		}
+		line.description = strings.Join(fields[i:], " ")
+		file.lines[line.name] = &line
+	}
+	return &file
+}
+
+type InterruptStats struct {
+	// IRQ number or name, like 24 or LOC, or softirq type, like NET_RX
+	Name string `json:"name" kind:"label"`
+	// Controller, trigger and devices of an IRQ line
+	Description string             `json:"description" kind:"label"`
+	Rate        float64            `json:"rate" kind:"counter" unit:"per_second"`
+	CpuRates    map[string]float64 `json:"cpu_rates" kind:"counter" unit:"per_second"`
+}
+
+/**
+ * Counters of the lines of both samples. A CPU going offline or online
+ * shifts the columns, its rates are zero until the next sample.
+ */
+func newInterruptsStats(prev, curr *interruptsFile, seconds float64) []*InterruptStats {
+	interruptsStats := []*InterruptStats{}
+	if prev == nil || curr == nil {
+		return interruptsStats
+	}
+	sameCpus := strings.Join(prev.cpus, " ") == strings.Join(curr.cpus, " ")
+
+	for name, currLine := range curr.lines {
+		prevLine, present := prev.lines[name]
+		if !present {
+			continue
+		}
+		interruptStats := InterruptStats{Name: name, Description: currLine.description}
+		interruptStats.CpuRates = make(map[string]float64)
+		for i, count := range currLine.counts {
+			if i >= len(prevLine.counts) || !sameCpus {
+				break
+			}
+			rate := getCounterRate(prevLine.counts[i], count, seconds)
+			interruptStats.Rate += rate
+			// Lines like ERR and MIS have a single total instead of per CPU counts
+			if len(currLine.counts) == len(curr.cpus) {
+				interruptStats.CpuRates[curr.cpus[i]] = rate
+			}
+		}
+		interruptsStats = append(interruptsStats, &interruptStats)
+	}
+	sort.Slice(interruptsStats, func(i, j int) bool {
+		if interruptsStats[i].Rate != interruptsStats[j].Rate {
+			return interruptsStats[i].Rate > interruptsStats[j].Rate
+		}
+		return interruptsStats[i].Name < interruptsStats[j].Name
+	})
+	return interruptsStats
+}
+
+/**
+ * Per CPU rates show IRQ affinity imbalance, like every NIC queue
+ * interrupting CPU0, and which CPUs do the NET_RX softirq work
+ */
+type LinuxInterruptsStats struct {
+	TopIrqs  []*InterruptStats `json:"top_irqs"`
+	Softirqs []*InterruptStats `json:"softirqs"`
+}
+
+func NewLinuxInterruptsStats() *LinuxInterruptsStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+
+	interruptsStats := LinuxInterruptsStats{}
+	interruptsStats.TopIrqs = newInterruptsStats(previous.interrupts, current.interrupts, seconds)
+	if InterruptsTopCount > 0 && len(interruptsStats.TopIrqs) > InterruptsTopCount {
+		interruptsStats.TopIrqs = interruptsStats.TopIrqs[:InterruptsTopCount]
+	}
+	interruptsStats.Softirqs = newInterruptsStats(previous.softirqs, current.softirqs, seconds)
+	return &interruptsStats
+}
@@ -0,0 +1,104 @@
+package stats
+
+import (
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+/**
+ * System wide tables of the kernel, from /proc/sys. A missing file,
+ * like aio on kernels built without it, reads as 0.
+ *
+ *   fs/file-nr   281 0 612720     allocated, free (always 0 since 2.6), max
+ *   fs/inode-nr  112561 3021      allocated, free
+ *
+ * Inodes have had no upper limit since 2.6, inode-max is gone and free
+ * only counts unused inodes still cached, so they come without a usage
+ * percentage.
+ */
+type kernelLimitsSample struct {
+	files       []uint64
+	filesMax    uint64
+	inodes      []uint64
+	pidMax      uint64
+	threadsMax  uint64
+	entropy     uint64
+	entropyPool uint64
+	aio         uint64
+	aioMax      uint64
+	// Threads of the host, from /proc/loadavg
+	threads uint64
+}
+
+func readKernelLimits(procPath string) *kernelLimitsSample {
+	limits := kernelLimitsSample{}
+	limits.files = readSysctlValues(procPath + "sys/fs/file-nr")
+	limits.filesMax = readSysctlValue(procPath + "sys/fs/file-max")
+	limits.inodes = readSysctlValues(procPath + "sys/fs/inode-nr")
+	limits.pidMax = readSysctlValue(procPath + "sys/kernel/pid_max")
+	limits.threadsMax = readSysctlValue(procPath + "sys/kernel/threads-max")
+	limits.entropy = readSysctlValue(procPath + "sys/kernel/random/entropy_avail")
+	limits.entropyPool = readSysctlValue(procPath + "sys/kernel/random/poolsize")
+	limits.aio = readSysctlValue(procPath + "sys/fs/aio-nr")
+	limits.aioMax = readSysctlValue(procPath + "sys/fs/aio-max-nr")
+	if loadavg, err := linuxproc.ReadLoadAvg(procPath + "loadavg"); err == nil {
+		limits.threads = loadavg.ProcessTotal
+	}
+	return &limits
+}
+
+/**
+ * Usage of the kernel tables that fail allocations once full: "Too many
+ * open files in system", fork() failing with EAGAIN, io_setup() failing
+ * with EAGAIN. Thread ids come from the pid space, so the threads count
+ * against pid_max as well as threads-max. Since Linux 5.18 the entropy
+ * pool is always full and entropy_avail stays at 256.
+ */
+type LinuxKernelLimitsStats struct {
+	FilesAllocated             uint64  `json:"files_allocated" kind:"gauge" unit:"count"`
+	FilesMax                   uint64  `json:"files_max" kind:"gauge" unit:"count"`
+	FilesUsedPercentage        float64 `json:"files_used_percentage" kind:"gauge" unit:"percent"`
+	InodesAllocated            uint64  `json:"inodes_allocated" kind:"gauge" unit:"count"`
+	InodesFree                 uint64  `json:"inodes_free" kind:"gauge" unit:"count"`
+	Threads                    uint64  `json:"threads" kind:"gauge" unit:"count"`
+	PidMax                     uint64  `json:"pid_max" kind:"gauge" unit:"count"`
+	PidUsedPercentage          float64 `json:"pid_used_percentage" kind:"gauge" unit:"percent"`
+	ThreadsMax                 uint64  `json:"threads_max" kind:"gauge" unit:"count"`
+	ThreadsUsedPercentage      float64 `json:"threads_used_percentage" kind:"gauge" unit:"percent"`
+	EntropyAvailable           uint64  `json:"entropy_available" kind:"gauge" unit:"count"`
+	EntropyPoolSize            uint64  `json:"entropy_pool_size" kind:"gauge" unit:"count"`
+	EntropyAvailablePercentage float64 `json:"entropy_available_percentage" kind:"gauge" unit:"percent"`
+	AioRequests                uint64  `json:"aio_requests" kind:"gauge" unit:"count"`
+	AioMax                     uint64  `json:"aio_max" kind:"gauge" unit:"count"`
+	AioUsedPercentage          float64 `json:"aio_used_percentage" kind:"gauge" unit:"percent"`
+}
+
+func NewLinuxKernelLimitsStats() *LinuxKernelLimitsStats {
+	_, current := SharedStatsPeriod.GetStatsSamples()
+	if current.kernelLimits == nil {
+		return nil
+	}
+	limits := current.kernelLimits
+
+	limitsStats := LinuxKernelLimitsStats{}
+	if len(limits.files) > 0 {
+		limitsStats.FilesAllocated = limits.files[0]
+	}
+	limitsStats.FilesMax = limits.filesMax
+	limitsStats.FilesUsedPercentage = getLimitPercentage(limitsStats.FilesAllocated, limits.filesMax)
+	if len(limits.inodes) > 1 {
+		limitsStats.InodesAllocated = limits.inodes[0]
+		limitsStats.InodesFree = limits.inodes[1]
+	}
+	limitsStats.Threads = limits.threads
+	limitsStats.PidMax = limits.pidMax
+	limitsStats.PidUsedPercentage = getLimitPercentage(limits.threads, limits.pidMax)
+	limitsStats.ThreadsMax = limits.threadsMax
+	limitsStats.ThreadsUsedPercentage = getLimitPercentage(limits.threads, limits.threadsMax)
+	limitsStats.EntropyAvailable = limits.entropy
+	limitsStats.EntropyPoolSize = limits.entropyPool
+	limitsStats.EntropyAvailablePercentage = getLimitPercentage(limits.entropy, limits.entropyPool)
+	limitsStats.AioRequests = limits.aio
+	limitsStats.AioMax = limits.aioMax
+	limitsStats.AioUsedPercentage = getLimitPercentage(limits.aio, limits.aioMax)
+	return &limitsStats
+}
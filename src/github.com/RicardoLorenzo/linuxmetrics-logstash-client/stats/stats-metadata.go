@@ -0,0 +1,76 @@
+package stats
+
+/**
+ * Metadata of the container a process or cgroup belongs to, resolved
+ * from the container ID by the registered providers (Kubernetes,
+ * container runtimes)
+ */
+type ContainerMetadata struct {
+	Name           string            `json:"name" kind:"label"`
+	Image          string            `json:"image" kind:"label"`
+	Labels         map[string]string `json:"labels" kind:"label"`
+	ComposeProject string            `json:"compose_project" kind:"label"`
+	Namespace      string            `json:"namespace" kind:"label"`
+	PodName        string            `json:"pod_name" kind:"label"`
+	PodLabels      map[string]string `json:"pod_labels" kind:"label"`
+}
+
+// Fills the fields that are still empty with the ones of other
+func (metadata *ContainerMetadata) merge(other *ContainerMetadata) {
+	if metadata.Name == "" {
+		metadata.Name = other.Name
+	}
+	if metadata.Image == "" {
+		metadata.Image = other.Image
+	}
+	if metadata.Labels == nil {
+		metadata.Labels = other.Labels
+	}
+	if metadata.ComposeProject == "" {
+		metadata.ComposeProject = other.ComposeProject
+	}
+	if metadata.Namespace == "" {
+		metadata.Namespace = other.Namespace
+	}
+	if metadata.PodName == "" {
+		metadata.PodName = other.PodName
+	}
+	if metadata.PodLabels == nil {
+		metadata.PodLabels = other.PodLabels
+	}
+}
+
+type ContainerMetadataProvider interface {
+	/**
+	 * Returns nil when the container is unknown. The container ID is
+	 * empty for pod level cgroups.
+	 */
+	GetContainerMetadata(containerId, podUid string) *ContainerMetadata
+}
+
+var ContainerMetadataProviders []ContainerMetadataProvider
+
+/**
+ * Providers are asked in registration order, on a Kubernetes node using
+ * Docker both know the container and their fields are merged
+ */
+func getContainerMetadata(containerId, podUid string) *ContainerMetadata {
+	if containerId == "" && podUid == "" {
+		return nil
+	}
+	var merged *ContainerMetadata
+	for _, provider := range ContainerMetadataProviders {
+		metadata := provider.GetContainerMetadata(containerId, podUid)
+		if metadata == nil {
+			continue
+		}
+		if merged == nil {
+			// Providers share their cached entries, never modify them
+			copied := *metadata
+			merged = &copied
+			continue
+		}
+		merged.merge(metadata)
+	}
+	return merged
+}
@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"reflect"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+/**
+ * Counters from /proc/net/netstat reported when no allowlist is
+ * configured. Names are the kernel ones, as printed by nstat.
+ */
+var NetstatFields = []string{
+	"ListenOverflows",
+	"ListenDrops",
+	"TCPTimeouts",
+	"TCPLossProbes",
+	"TCPLossProbeRecovery",
+	"TCPFastRetrans",
+	"TCPSlowStartRetrans",
+	"TCPSynRetrans",
+	"TCPRetransFail",
+	"SyncookiesSent",
+	"SyncookiesRecv",
+	"SyncookiesFailed",
+	"TCPBacklogDrop",
+	"TCPReqQFullDrop",
+	"TCPReqQFullDoCookies",
+	"PruneCalled",
+	"RcvPruned",
+	"OfoPruned",
+	"TCPOFODrop",
+	"TCPAbortOnData",
+	"TCPAbortOnClose",
+	"TCPAbortOnMemory",
+	"TCPAbortOnTimeout",
+	"TCPAbortOnLinger",
+	"TCPAbortFailed",
+	"TCPMemoryPressures",
+	"TCPTimeWaitOverflow",
+	"InOctets",
+	"OutOctets",
+}
+
+/**
+ * The IpExt block follows the TcpExt one in /proc/net/netstat, every
+ * field from InNoRoutes onwards belongs to it.
+ */
+const firstIpExtField string = "InNoRoutes"
+
+/**
+ * Extended TCP and IP counters as per second rates, keyed by the
+ * snake case name of the kernel counter (listen_overflows, in_octets)
+ */
+type LinuxNetstatStats struct {
+	TcpExt map[string]float64 `json:"tcp_ext" kind:"counter" unit:"per_second"`
+	IpExt  map[string]float64 `json:"ip_ext" kind:"counter" unit:"per_second"`
+}
+
+func (netstatStats *LinuxNetstatStats) isAllowed(name string) bool {
+	for _, field := range NetstatFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewLinuxNetstatStats() *LinuxNetstatStats {
+	netstatStats := LinuxNetstatStats{}
+	netstatStats.TcpExt = make(map[string]float64)
+	netstatStats.IpExt = make(map[string]float64)
+
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+
+	// Additional memory access protection avoiding null references
+	if !SharedStatsPeriod.HasPreviousSamples() || previous.netstat == nil || current.netstat == nil {
+		return &netstatStats
+	}
+
+	seconds := current.getElapsedSeconds(previous)
+	prevValues := reflect.ValueOf(previous.netstat).Elem()
+	currValues := reflect.ValueOf(current.netstat).Elem()
+	fields := reflect.TypeOf(linuxproc.NetStat{})
+
+	section := netstatStats.TcpExt
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		if field.Name == firstIpExtField {
+			section = netstatStats.IpExt
+		}
+		if !netstatStats.isAllowed(field.Name) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		section[name] = getCounterRate(prevValues.Field(i).Uint(), currValues.Field(i).Uint(), seconds)
+	}
+
+	return &netstatStats
+}
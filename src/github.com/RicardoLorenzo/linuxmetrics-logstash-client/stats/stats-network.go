@@ -6,54 +6,64 @@ package stats
  */
 type LinuxNetworkStats struct {
 	// IP
-	IpForwarding uint64 `json:"ip_forwarding"`
-	IpForwDatagrams uint64 `json:"ip_forwarded"`
-  IpInReceives uint64 `json:"ip_in_received"`
-	IpInHdrErrors uint64 `json:"ip_in_header_errors"`
-	IpInAddrErrors uint64 `json:"ip_in_addr_errors"`
-	IpInDiscards  uint64 `json:"ip_in_discarded"`
+	IpForwarding uint64 `json:"ip_forwarding" kind:"gauge" unit:"flag"`
+	IpForwDatagrams uint64 `json:"ip_forwarded" kind:"counter" unit:"packets"`
+  IpInReceives uint64 `json:"ip_in_received" kind:"counter" unit:"packets"`
+	IpInHdrErrors uint64 `json:"ip_in_header_errors" kind:"counter" unit:"packets"`
+	IpInAddrErrors uint64 `json:"ip_in_addr_errors" kind:"counter" unit:"packets"`
+	IpInDiscards  uint64 `json:"ip_in_discarded" kind:"counter" unit:"packets"`
 	// Discarded because of an unknown or unsupported protocol
-	IpInUnknownProtos uint64 `json:"ip_in_unknown"`
-	IpInDelivers uint64 `json:"ip_in_delivered"`
+	IpInUnknownProtos uint64 `json:"ip_in_unknown" kind:"counter" unit:"packets"`
+	IpInDelivers uint64 `json:"ip_in_delivered" kind:"counter" unit:"packets"`
   // Does not include any of the IpForwDatagrams
-	IpOutRequests uint64 `json:"ip_out_requests"`
-	IpOutNoRoutes uint64 `json:"ip_out_noroute"`
-	IpOutDiscards uint64 `json:"ip_out_discarded"`
+	IpOutRequests uint64 `json:"ip_out_requests" kind:"counter" unit:"packets"`
+	IpOutNoRoutes uint64 `json:"ip_out_noroute" kind:"counter" unit:"packets"`
+	IpOutDiscards uint64 `json:"ip_out_discarded" kind:"counter" unit:"packets"`
 	// TCP
-	TcpRtoMax uint64 `json:"tcp_rto_max"`
-	TcpMaxConn uint64 `json:"tcp_max_connections"`
+	TcpRtoMax uint64 `json:"tcp_rto_max" kind:"gauge" unit:"ms"`
+	TcpMaxConn uint64 `json:"tcp_max_connections" kind:"gauge" unit:"count"`
 	/**
 	 * The number of times TCP connections have made a
    * direct transition to the SYN-SENT state from the
    * CLOSED state.
 	 */
-	TcpActiveOpens uint64 `json:"tcp_active_opened"`
+	TcpActiveOpens uint64 `json:"tcp_active_opened" kind:"counter" unit:"count"`
 	/**
 	 * The number of times TCP connections have made a
    * direct transition to the SYN-RCVD state from the
    * LISTEN state.
 	 */
-	TcpPassiveOpens uint64 `json:"tcp_active_opened"`
+	TcpPassiveOpens uint64 `json:"tcp_passive_opened" kind:"counter" unit:"count"`
 	/**
 	 * TCP connections for which the current state is
 	 * either ESTABLISHED or CLOSE-WAIT
 	 */
-	TcpCurrEstab uint64 `json:"tcp_current_established"`
-	TcpEstabResets uint64 `json:"tcp_established_reset"`
-	TcpRetransSegs uint64 `json:"tcp_retransmited_seg"`
-	TcpInSegs uint64 `json:"tcp_in_seg"`
-	TcpOutSegs uint64 `json:"tcp_out_seg"`
-	TcpInErrs  uint64 `json:"tcp_in_error"`
-	TcpOutRsts  uint64 `json:"tcp_out_rst"`
+	TcpCurrEstab uint64 `json:"tcp_current_established" kind:"gauge" unit:"count"`
+	TcpEstabResets uint64 `json:"tcp_established_reset" kind:"counter" unit:"count"`
+	TcpRetransSegs uint64 `json:"tcp_retransmited_seg" kind:"counter" unit:"segments"`
+	TcpInSegs uint64 `json:"tcp_in_seg" kind:"counter" unit:"segments"`
+	TcpOutSegs uint64 `json:"tcp_out_seg" kind:"counter" unit:"segments"`
+	TcpInErrs  uint64 `json:"tcp_in_error" kind:"counter" unit:"segments"`
+	TcpOutRsts  uint64 `json:"tcp_out_rst" kind:"counter" unit:"segments"`
 	/**
 	 * I'm summarizing in a single field all the receive and transmit
-	 * queues from all sockets.
+	 * queues from all sockets, both IPv4 and IPv6.
 	 *
 	 * https://www.kernel.org/doc/Documentation/networking/proc_net_tcp.txt
 	 */
-	TotalTCPSockets uint64 `json:"total_tcp_sockets"`
-	TotalTCPRxQueue uint64 `json:"total_tcp_rx_queue"`
-	TotalTCPTxQueue uint64 `json:"total_tcp_tx_queue"`
+	TotalTCPSockets uint64 `json:"total_tcp_sockets" kind:"gauge" unit:"count"`
+	TotalTCPRxQueue uint64 `json:"total_tcp_rx_queue" kind:"gauge" unit:"bytes"`
+	TotalTCPTxQueue uint64 `json:"total_tcp_tx_queue" kind:"gauge" unit:"bytes"`
+	// Per state socket counts over IPv4 and IPv6
+	TCPSockets *LinuxTCPSocketStats `json:"tcp_sockets"`
+	// TcpExt and IpExt from /proc/net/netstat
+	Netstat *LinuxNetstatStats `json:"netstat"`
+	// Per CPU backlog processing from /proc/net/softnet_stat
+	Softnet *LinuxSoftnetStats `json:"softnet"`
+	// Sockets and their memory against the limits, from /proc/net/sockstat
+	Sockstat *LinuxSockstatStats `json:"sockstat"`
+	// Connection tracking table, absent without the nf_conntrack module
+	Conntrack *LinuxConntrackStats `json:"conntrack"`
 }
 
 func NewLinuxNetworkStats() *LinuxNetworkStats {
@@ -96,5 +106,11 @@ func NewLinuxNetworkStats() *LinuxNetworkStats {
 	networkStats.TotalTCPRxQueue = rxQueue
 	networkStats.TotalTCPTxQueue = txQueue
 
+	networkStats.TCPSockets = NewLinuxTCPSocketStats()
+	networkStats.Netstat = NewLinuxNetstatStats()
+	networkStats.Softnet = NewLinuxSoftnetStats()
+	networkStats.Sockstat = NewLinuxSockstatStats()
+	networkStats.Conntrack = NewLinuxConntrackStats()
+
 	return &networkStats
 }
@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Root of the cgroup hierarchy, with one directory per controller on v1
+	CgroupPath string = "/sys/fs/cgroup/"
+	// cgroup v2 directories, relative to CgroupPath, whose pressure files are reported
+	PressureCgroups = []string{}
+)
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+/**
+ * A line of a PSI file:
+ *
+ *   some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+ *
+ * Averages are percentages of wall time, total is in microseconds.
+ *
+ * https://www.kernel.org/doc/html/latest/accounting/psi.html
+ */
+type pressureLine struct {
+	avg10  float64
+	avg60  float64
+	avg300 float64
+	total  uint64
+}
+
+type pressureFile struct {
+	some *pressureLine
+	full *pressureLine
+}
+
+func readPressureFile(path string) (*pressureFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pressure := pressureFile{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		values := pressureLine{}
+		for _, field := range fields[1:] {
+			tokens := strings.SplitN(field, "=", 2)
+			if len(tokens) != 2 {
+				continue
+			}
+			switch tokens[0] {
+			case "avg10":
+				values.avg10, _ = strconv.ParseFloat(tokens[1], 64)
+			case "avg60":
+				values.avg60, _ = strconv.ParseFloat(tokens[1], 64)
+			case "avg300":
+				values.avg300, _ = strconv.ParseFloat(tokens[1], 64)
+			case "total":
+				values.total, _ = strconv.ParseUint(tokens[1], 10, 64)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			pressure.some = &values
+		case "full":
+			pressure.full = &values
+		}
+	}
+	return &pressure, nil
+}
+
+/**
+ * Reads <directory>/<resource><suffix> for cpu, memory and io. Kernels
+ * without PSI, or booted with psi=0, have no such files and an empty
+ * map is returned.
+ */
+func readPressureFiles(directory, suffix string) map[string]*pressureFile {
+	files := make(map[string]*pressureFile)
+	for _, resource := range pressureResources {
+		// ENOENT without PSI support, EOPNOTSUPP when compiled in but disabled
+		if pressure, err := readPressureFile(directory + resource + suffix); err == nil {
+			files[resource] = pressure
+		}
+	}
+	return files
+}
+
+type PressureStats struct {
+	Avg10  float64 `json:"avg10" kind:"gauge" unit:"percent"`
+	Avg60  float64 `json:"avg60" kind:"gauge" unit:"percent"`
+	Avg300 float64 `json:"avg300" kind:"gauge" unit:"percent"`
+	// Microseconds of stall per second of wall time
+	TotalRate float64 `json:"total_rate" kind:"counter" unit:"us_per_second"`
+}
+
+/**
+ * "some" is the share of time at least one task was stalled on the
+ * resource, "full" the share of time all non-idle tasks were. The CPU
+ * "full" line only exists on 5.13+ kernels and for cgroups.
+ */
+type LinuxPressureResourceStats struct {
+	Some *PressureStats `json:"some"`
+	Full *PressureStats `json:"full"`
+}
+
+type LinuxPressureStats struct {
+	Cpu    *LinuxPressureResourceStats `json:"cpu"`
+	Memory *LinuxPressureResourceStats `json:"memory"`
+	IO     *LinuxPressureResourceStats `json:"io"`
+}
+
+func newPressureStats(prev, curr *pressureLine, seconds float64) *PressureStats {
+	if curr == nil {
+		return nil
+	}
+	pressureStats := PressureStats{}
+	pressureStats.Avg10 = curr.avg10
+	pressureStats.Avg60 = curr.avg60
+	pressureStats.Avg300 = curr.avg300
+	if prev != nil {
+		pressureStats.TotalRate = getCounterRate(prev.total, curr.total, seconds)
+	}
+	return &pressureStats
+}
+
+func newPressureResourceStats(prev, curr *pressureFile, seconds float64) *LinuxPressureResourceStats {
+	if curr == nil {
+		return nil
+	}
+	if prev == nil {
+		prev = &pressureFile{}
+	}
+	resourceStats := LinuxPressureResourceStats{}
+	resourceStats.Some = newPressureStats(prev.some, curr.some, seconds)
+	resourceStats.Full = newPressureStats(prev.full, curr.full, seconds)
+	return &resourceStats
+}
+
+/**
+ * Returns nil when none of the resources has pressure information
+ */
+func newLinuxPressureStats(prev, curr map[string]*pressureFile, seconds float64) *LinuxPressureStats {
+	if len(curr) == 0 {
+		return nil
+	}
+	pressureStats := LinuxPressureStats{}
+	pressureStats.Cpu = newPressureResourceStats(prev["cpu"], curr["cpu"], seconds)
+	pressureStats.Memory = newPressureResourceStats(prev["memory"], curr["memory"], seconds)
+	pressureStats.IO = newPressureResourceStats(prev["io"], curr["io"], seconds)
+	return &pressureStats
+}
+
+type LinuxPressureSectionStats struct {
+	Host *LinuxPressureStats `json:"host"`
+	// Keyed by cgroup path relative to the cgroup root
+	Cgroups map[string]*LinuxPressureStats `json:"cgroups"`
+}
+
+func NewLinuxPressureStats() *LinuxPressureSectionStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+
+	if len(current.pressure) == 0 && len(current.cgroupPressure) == 0 {
+		return nil
+	}
+
+	sectionStats := LinuxPressureSectionStats{}
+	sectionStats.Host = newLinuxPressureStats(previous.pressure, current.pressure, seconds)
+	sectionStats.Cgroups = make(map[string]*LinuxPressureStats)
+	for cgroup, files := range current.cgroupPressure {
+		if pressureStats := newLinuxPressureStats(previous.cgroupPressure[cgroup], files, seconds); pressureStats != nil {
+			sectionStats.Cgroups[cgroup] = pressureStats
+		}
+	}
+	return &sectionStats
+}
@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+/**
+ * Where a process runs, from /proc/<pid>/cgroup:
+ *
+ *   0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/cri-containerd-<id>.scope
+ *   4:memory:/kubepods/burstable/pod<uid>/<id>
+ *   1:name=systemd:/system.slice/sshd.service
+ */
+type processCgroup struct {
+	path         string
+	containerId  string
+	podUid       string
+	qosClass     string
+	systemdUnit  string
+	systemdSlice string
+}
+
+func getQosClass(path string) string {
+	if !strings.Contains(path, "kubepods") {
+		return ""
+	}
+	if strings.Contains(path, "besteffort") {
+		return "BestEffort"
+	}
+	if strings.Contains(path, "burstable") {
+		return "Burstable"
+	}
+	// Guaranteed pods are placed directly under kubepods
+	return "Guaranteed"
+}
+
+func (cgroup *processCgroup) parseSystemdPath(path string) {
+	elements := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(elements) - 1; i >= 0; i-- {
+		if cgroup.systemdUnit == "" && cgroup.systemdSlice == "" &&
+			(strings.HasSuffix(elements[i], ".service") || strings.HasSuffix(elements[i], ".scope")) {
+			cgroup.systemdUnit = elements[i]
+		}
+		if cgroup.systemdSlice == "" && strings.HasSuffix(elements[i], ".slice") {
+			cgroup.systemdSlice = elements[i]
+		}
+	}
+}
+
+func parseProcessCgroup(data string) *processCgroup {
+	cgroup := processCgroup{}
+	var systemdPath string
+
+	for _, line := range strings.Split(data, "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		tokens := strings.SplitN(line, ":", 3)
+		if len(tokens) != 3 || tokens[2] == "/" {
+			continue
+		}
+		path := tokens[2]
+		// The unified hierarchy, or the systemd one on v1, holds the units
+		if tokens[0] == "0" || tokens[1] == "name=systemd" {
+			systemdPath = path
+		}
+		if cgroup.path == "" || tokens[0] == "0" {
+			cgroup.path = path
+		}
+		containerId, podUid := parseCgroupPath(path)
+		if cgroup.containerId == "" {
+			cgroup.containerId = containerId
+		}
+		if cgroup.podUid == "" {
+			cgroup.podUid = podUid
+		}
+	}
+
+	cgroup.qosClass = getQosClass(cgroup.path)
+	cgroup.parseSystemdPath(systemdPath)
+	return &cgroup
+}
+
+func readProcessCgroup(path string) (*processCgroup, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessCgroup(string(data)), nil
+}
@@ -0,0 +1,183 @@
+package stats
+
+import (
+	"encoding/json"
+	"log"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+const (
+	ProcessStartedEvent string = "process_started"
+	ProcessExitedEvent  string = "process_exited"
+	ProcessChurnEvent   string = "process_churn"
+)
+
+var (
+	// Sends process_started, process_exited and process_churn documents to ProcessEventChannel
+	ProcessEventsEnabled bool = false
+	// JSON documents, dropped when nobody reads them fast enough
+	ProcessEventChannel chan string = make(chan string, 1000)
+)
+
+/**
+ * Lifecycle document sent when a process shows up in, or disappears
+ * from, a sample. Processes that start and exit between two samples are
+ * not seen, the process_churn document counts them among its unseen
+ * forks.
+ */
+type LinuxProcessEvent struct {
+	Type        string `json:"type" kind:"label"`
+	Hostname    string `json:"hostname" kind:"label"`
+	Pid         uint64 `json:"pid" kind:"label"`
+	Ppid        int64  `json:"ppid" kind:"label"`
+	Comm        string `json:"comm" kind:"label"`
+	CmdLine     string `json:"cmdline" kind:"label"`
+	Uid         uint64 `json:"uid" kind:"label"`
+	User        string `json:"user" kind:"label"`
+	Cgroup      string `json:"cgroup" kind:"label"`
+	ContainerId string `json:"container_id" kind:"label"`
+	// Milliseconds since the epoch
+	StartTime uint64 `json:"start_time" kind:"label"`
+	// Up to the sample that found the process, or for exits the last
+	// sample that still had it
+	Lifetime uint64 `json:"lifetime" kind:"gauge" unit:"ms"`
+	// Totals over the life of the process, only on exits. They are read
+	// from the last sample that had the process, so they can be up to one
+	// sample interval short of the values at the exit.
+	UserCpuTime   uint64 `json:"user_cpu_time,omitempty" kind:"gauge" unit:"ms"`
+	SystemCpuTime uint64 `json:"system_cpu_time,omitempty" kind:"gauge" unit:"ms"`
+	IOReadBytes   uint64 `json:"io_read_bytes,omitempty" kind:"gauge" unit:"bytes"`
+	IOWriteBytes  uint64 `json:"io_write_bytes,omitempty" kind:"gauge" unit:"bytes"`
+}
+
+func newLinuxProcessEvent(eventType string, sample StatsSample, process *linuxproc.Process) *LinuxProcessEvent {
+	event := LinuxProcessEvent{}
+	event.Type = eventType
+	event.Hostname = sample.hostname
+	event.Pid = process.Status.Pid
+	event.Ppid = process.Status.PPid
+	event.Comm = process.Status.Name
+	event.CmdLine = getProcessCmdline(process)
+	event.Uid = process.Status.RealUid
+	event.User = getUserName(sample.users, process.Status.RealUid)
+	if cgroup, present := sample.processCgroups[event.Pid]; present {
+		event.Cgroup = cgroup.path
+		event.ContainerId = cgroup.containerId
+	}
+	event.StartTime = getProcessStartTime(sample.stat.BootTime, process)
+	if sample.time > event.StartTime {
+		event.Lifetime = sample.time - event.StartTime
+	}
+
+	if eventType == ProcessExitedEvent {
+		event.UserCpuTime = process.Stat.Utime * 1000 / clockTicksPerSecond
+		event.SystemCpuTime = process.Stat.Stime * 1000 / clockTicksPerSecond
+		event.IOReadBytes = process.IO.ReadBytes
+		event.IOWriteBytes = process.IO.WriteBytes
+	}
+	return &event
+}
+
+func isSameProcess(a, b *linuxproc.Process) bool {
+	return a != nil && b != nil && a.Stat.Starttime == b.Stat.Starttime
+}
+
+/**
+ * Lifecycle events between two consecutive samples, a reused pid gives
+ * an exit and a start. Pids one of the samples failed to read are
+ * skipped, a failed read is no proof that the process exited or started.
+ */
+func NewLinuxProcessEvents(previous, current StatsSample) []*LinuxProcessEvent {
+	events := []*LinuxProcessEvent{}
+	if previous.time == 0 || previous.stat == nil || current.stat == nil {
+		return events
+	}
+
+	previousProcesses := getProcessesByPid(previous)
+	currentProcesses := getProcessesByPid(current)
+
+	for _, process := range previous.processes {
+		if current.processReadFailed[process.Status.Pid] {
+			continue
+		}
+		if !isSameProcess(process, currentProcesses[process.Status.Pid]) {
+			events = append(events, newLinuxProcessEvent(ProcessExitedEvent, previous, process))
+		}
+	}
+	for _, process := range current.processes {
+		// Unless it started after the previous sample, which then missed another process
+		if previous.processReadFailed[process.Status.Pid] &&
+			getProcessStartTime(current.stat.BootTime, process) <= previous.time {
+			continue
+		}
+		if !isSameProcess(process, previousProcesses[process.Status.Pid]) {
+			events = append(events, newLinuxProcessEvent(ProcessStartedEvent, current, process))
+		}
+	}
+	return events
+}
+
+/**
+ * Process creations between two consecutive samples. Forks come from
+ * the processes counter of /proc/stat, which counts every fork and
+ * clone, threads included, so unseen forks are the threads created
+ * plus the processes that started and exited between the samples, like
+ * short-lived cron jobs. A rise of unseen forks without a rise of
+ * threads in the osmetrics documents points at such processes.
+ */
+type LinuxProcessChurnEvent struct {
+	Type     string `json:"type" kind:"label"`
+	Hostname string `json:"hostname" kind:"label"`
+	Interval uint64 `json:"interval" kind:"gauge" unit:"ms"`
+	Forks    uint64 `json:"forks" kind:"counter" unit:"count"`
+	// process_started and process_exited documents of the interval
+	Started uint64 `json:"started" kind:"counter" unit:"count"`
+	Exited  uint64 `json:"exited" kind:"counter" unit:"count"`
+	Unseen  uint64 `json:"unseen" kind:"counter" unit:"count"`
+}
+
+func NewLinuxProcessChurnEvent(previous, current StatsSample, events []*LinuxProcessEvent) *LinuxProcessChurnEvent {
+	if previous.time == 0 || previous.stat == nil || current.stat == nil {
+		return nil
+	}
+
+	event := LinuxProcessChurnEvent{}
+	event.Type = ProcessChurnEvent
+	event.Hostname = current.hostname
+	event.Interval = getCounterDelta(previous.time, current.time)
+	event.Forks = getCounterDelta(previous.stat.Processes, current.stat.Processes)
+	for _, processEvent := range events {
+		switch processEvent.Type {
+		case ProcessStartedEvent:
+			event.Started++
+		case ProcessExitedEvent:
+			event.Exited++
+		}
+	}
+	event.Unseen = getCounterDelta(event.Started, event.Forks)
+	return &event
+}
+
+func sendProcessEvent(eventType string, event interface{}) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Fail to encode process event", err)
+		return
+	}
+	select {
+	case ProcessEventChannel <- string(message):
+	default:
+		log.Println("Process event channel is full, dropping", eventType, "event")
+	}
+}
+
+func sendProcessEvents(previous, current StatsSample) {
+	events := NewLinuxProcessEvents(previous, current)
+	for _, event := range events {
+		sendProcessEvent(event.Type, event)
+	}
+	if churn := NewLinuxProcessChurnEvent(previous, current, events); churn != nil {
+		sendProcessEvent(churn.Type, churn)
+	}
+}
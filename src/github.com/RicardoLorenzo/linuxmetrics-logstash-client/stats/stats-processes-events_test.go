@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+func newTestProcess(pid, starttime uint64) *linuxproc.Process {
+	process := linuxproc.Process{}
+	process.Status.Pid = pid
+	process.Stat.Starttime = starttime
+	return &process
+}
+
+// Sample taken at the given number of clock ticks since boot
+func newTestSample(ticks uint64, processes ...*linuxproc.Process) StatsSample {
+	bootTime := time.Unix(1600000000, 0)
+	sample := StatsSample{stat: &linuxproc.Stat{BootTime: bootTime}}
+	sample.time = uint64(bootTime.UnixNano()/int64(time.Millisecond)) + ticks*1000/clockTicksPerSecond
+	sample.processes = processes
+	sample.processReadFailed = make(map[uint64]bool)
+	return sample
+}
+
+func getTestEvents(events []*LinuxProcessEvent) []string {
+	names := []string{}
+	for _, event := range events {
+		names = append(names, fmt.Sprintf("%s %d", event.Type, event.Pid))
+	}
+	return names
+}
+
+func TestProcessEventsReadFailure(t *testing.T) {
+	first := newTestSample(1000, newTestProcess(1, 10), newTestProcess(2, 20), newTestProcess(3, 30))
+	// 2 denied, 3 exited and its pid reused
+	second := newTestSample(2000, newTestProcess(1, 10), newTestProcess(3, 1500))
+	second.processReadFailed[2] = true
+	expected := []string{ProcessExitedEvent + " 3", ProcessStartedEvent + " 3"}
+	if events := getTestEvents(NewLinuxProcessEvents(first, second)); !reflect.DeepEqual(events, expected) {
+		t.Errorf("events %v, expected %v", events, expected)
+	}
+
+	// 2 readable again, 4 started after the sample that could not read its pid
+	third := newTestSample(3000, newTestProcess(1, 10), newTestProcess(2, 20), newTestProcess(3, 1500),
+		newTestProcess(4, 2500))
+	second.processReadFailed[4] = true
+	expected = []string{ProcessStartedEvent + " 4"}
+	if events := getTestEvents(NewLinuxProcessEvents(second, third)); !reflect.DeepEqual(events, expected) {
+		t.Errorf("events %v, expected %v", events, expected)
+	}
+}
@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Soft and hard limit, 0 when unlimited
+type processLimit struct {
+	soft uint64
+	hard uint64
+}
+
+/**
+ * From /proc/<pid>/limits:
+ *
+ *   Limit                     Soft Limit           Hard Limit           Units
+ *   Max stack size            8388608              unlimited            bytes
+ *   Max processes             23959                23959                processes
+ *   Max open files            1024                 524288               files
+ *   Max locked memory         8388608              8388608              bytes
+ */
+type processLimits struct {
+	openFiles    processLimit
+	processes    processLimit
+	lockedMemory processLimit
+	stack        processLimit
+}
+
+/**
+ * Limits are world readable, the open descriptors and their socket
+ * inodes need the rights to ptrace the process and are nil without them
+ */
+type processFiles struct {
+	fds          *uint64
+	limits       *processLimits
+	socketInodes []uint64
+}
+
+func parseLimitValue(value string) uint64 {
+	if value == "unlimited" {
+		return 0
+	}
+	number, _ := strconv.ParseUint(value, 10, 64)
+	return number
+}
+
+func parseProcessLimits(data string) *processLimits {
+	limits := processLimits{}
+	for _, line := range strings.Split(data, "\n") {
+		// Limit names contain spaces, the values start at a fixed column
+		if len(line) < 26 {
+			continue
+		}
+		fields := strings.Fields(line[26:])
+		if len(fields) < 2 {
+			continue
+		}
+		limit := processLimit{soft: parseLimitValue(fields[0]), hard: parseLimitValue(fields[1])}
+		switch strings.TrimSpace(line[:26]) {
+		case "Max open files":
+			limits.openFiles = limit
+		case "Max processes":
+			limits.processes = limit
+		case "Max locked memory":
+			limits.lockedMemory = limit
+		case "Max stack size":
+			limits.stack = limit
+		}
+	}
+	return &limits
+}
+
+/**
+ * Returns nil when the process has exited. The descriptors of a process
+ * of another user are left out when the collector lacks CAP_SYS_PTRACE.
+ */
+func readProcessFiles(processPath string) *processFiles {
+	files := processFiles{}
+	if data, err := ioutil.ReadFile(processPath + "/limits"); err == nil {
+		files.limits = parseProcessLimits(string(data))
+	}
+
+	if names, err := readDirectoryNames(processPath + "/fd"); err == nil {
+		fds := uint64(len(names))
+		files.fds = &fds
+		if ProcessSocketsEnabled {
+			files.socketInodes = readSocketInodes(processPath+"/fd", names)
+		}
+	}
+
+	if files.limits == nil && files.fds == nil {
+		return nil
+	}
+	return &files
+}
+
+func readDirectoryNames(path string) ([]string, error) {
+	directory, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer directory.Close()
+	return directory.Readdirnames(-1)
+}
+
+func getLimitPercentage(used, limit uint64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return 100.0 * float64(used) / float64(limit)
+}
@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const limitsDocument = `Limit                     Soft Limit           Hard Limit           Units
+Max stack size            8388608              unlimited            bytes
+Max processes             23959                23959                processes
+Max open files            1024                 524288               files
+Max locked memory         8388608              8388608              bytes
+`
+
+func TestReadProcessFilesWithoutFdAccess(t *testing.T) {
+	directory, err := ioutil.TempDir("", "process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	if err := ioutil.WriteFile(filepath.Join(directory, "limits"), []byte(limitsDocument), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	// No fd directory, as seen for a process of another user
+	files := readProcessFiles(directory)
+	if files == nil || files.limits == nil {
+		t.Fatal("limits dropped without the fd directory")
+	}
+	if files.fds != nil || files.socketInodes != nil {
+		t.Errorf("fds %v and socket inodes %v without the fd directory", files.fds, files.socketInodes)
+	}
+	expected := processLimits{
+		openFiles:    processLimit{soft: 1024, hard: 524288},
+		processes:    processLimit{soft: 23959, hard: 23959},
+		lockedMemory: processLimit{soft: 8388608, hard: 8388608},
+		stack:        processLimit{soft: 8388608},
+	}
+	if *files.limits != expected {
+		t.Errorf("limits %+v, expected %+v", *files.limits, expected)
+	}
+
+	if err := os.Mkdir(filepath.Join(directory, "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"0", "1", "2"} {
+		if err := os.Symlink("/dev/null", filepath.Join(directory, "fd", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files := readProcessFiles(directory); files == nil || files.fds == nil || *files.fds != 3 {
+		t.Errorf("files %+v, expected 3 descriptors", files)
+	}
+
+	if files := readProcessFiles(filepath.Join(directory, "exited")); files != nil {
+		t.Errorf("files %+v of an exited process", files)
+	}
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"sort"
+)
+
+var (
+	// Processes reported after filtering, 0 reports all of them
+	ProcessTopCount int = 0
+	// Top-N order, one of "cpu", "rss", "io" or "fd"
+	ProcessTopSort string = "cpu"
+	// Regular expressions, a process must match one include expression of
+	// each non-empty include list and no exclude expression
+	ProcessIncludeCmdlines = []string{}
+	ProcessExcludeCmdlines = []string{}
+	ProcessIncludeComms    = []string{}
+	ProcessExcludeComms    = []string{}
+	// Matched against the user name, or the uid when it has no name
+	ProcessIncludeUsers = []string{}
+	ProcessExcludeUsers = []string{}
+	// Matched against the cgroup path
+	ProcessIncludeCgroups = []string{}
+	ProcessExcludeCgroups = []string{}
+	// Regular expressions on the comm or the cmdline of processes that are
+	// always reported, regardless of the filters and the top-N selection
+	ProcessAlwaysInclude = []string{}
+
+	processIncludeCmdlines = newPatternList(&ProcessIncludeCmdlines)
+	processExcludeCmdlines = newPatternList(&ProcessExcludeCmdlines)
+	processIncludeComms    = newPatternList(&ProcessIncludeComms)
+	processExcludeComms    = newPatternList(&ProcessExcludeComms)
+	processIncludeUsers    = newPatternList(&ProcessIncludeUsers)
+	processExcludeUsers    = newPatternList(&ProcessExcludeUsers)
+	processIncludeCgroups  = newPatternList(&ProcessIncludeCgroups)
+	processExcludeCgroups  = newPatternList(&ProcessExcludeCgroups)
+	processAlwaysInclude   = newPatternList(&ProcessAlwaysInclude)
+)
+
+func isPatternSelected(include, exclude *patternList, value string) bool {
+	if exclude.matches(value) {
+		return false
+	}
+	return include.isEmpty() || include.matches(value)
+}
+
+type processSelection struct {
+	always    []*LinuxProcessStats
+	candidate []*LinuxProcessStats
+	omitted   uint64
+}
+
+func newProcessSelection() *processSelection {
+	return &processSelection{}
+}
+
+func (selection *processSelection) isSelected(processStats *LinuxProcessStats) bool {
+	if !isPatternSelected(processIncludeCmdlines, processExcludeCmdlines, processStats.CmdLine) {
+		return false
+	}
+	if !isPatternSelected(processIncludeComms, processExcludeComms, processStats.Comm) {
+		return false
+	}
+	if !isPatternSelected(processIncludeUsers, processExcludeUsers, processStats.User) {
+		return false
+	}
+	return isPatternSelected(processIncludeCgroups, processExcludeCgroups, processStats.Cgroup)
+}
+
+func (selection *processSelection) add(processStats *LinuxProcessStats) {
+	if processAlwaysInclude.matches(processStats.Comm) || processAlwaysInclude.matches(processStats.CmdLine) {
+		selection.always = append(selection.always, processStats)
+		return
+	}
+	if !selection.isSelected(processStats) {
+		selection.omitted++
+		return
+	}
+	selection.candidate = append(selection.candidate, processStats)
+}
+
+func getProcessSortValue(processStats *LinuxProcessStats) uint64 {
+	switch ProcessTopSort {
+	case "rss":
+		return processStats.MemRssSize
+	case "io":
+		return processStats.IOReadBytes + processStats.IOWriteBytes
+	case "fd":
+		return processStats.FDUsed
+	}
+	return processStats.UserCpuUsage + processStats.SystemCpuUsage
+}
+
+/**
+ * Always included processes first, then the selected ones in ProcessTopSort
+ * order when limited to the top ProcessTopCount
+ */
+func (selection *processSelection) getProcesses() []*LinuxProcessStats {
+	processes := append([]*LinuxProcessStats{}, selection.always...)
+	candidate := selection.candidate
+	if ProcessTopCount > 0 {
+		sort.SliceStable(candidate, func(i, j int) bool {
+			return getProcessSortValue(candidate[i]) > getProcessSortValue(candidate[j])
+		})
+		if len(candidate) > ProcessTopCount {
+			selection.omitted += uint64(len(candidate) - ProcessTopCount)
+			candidate = candidate[:ProcessTopCount]
+		}
+	}
+	return append(processes, candidate...)
+}
@@ -0,0 +1,169 @@
+package stats
+
+import (
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// Groups processes not matching any rule by "comm", "user" or "service", empty disables it
+	ProcessGroupBy string = ""
+	// <group>:<regular expression> rules matched against the comm or the
+	// cmdline, in order, the first matching rule names the group. The
+	// configuration splits the rules on commas, so an expression cannot
+	// contain one, {1,3} has to be written out.
+	ProcessGroupRules = []string{}
+
+	processGroupRules = newProcessGroupRuleList(&ProcessGroupRules)
+)
+
+type processGroupRule struct {
+	name       string
+	expression *regexp.Regexp
+}
+
+type processGroupRuleList struct {
+	definitions *[]string
+	rules       []*processGroupRule
+	once        sync.Once
+}
+
+func newProcessGroupRuleList(definitions *[]string) *processGroupRuleList {
+	return &processGroupRuleList{definitions: definitions}
+}
+
+func (list *processGroupRuleList) compile() {
+	for _, definition := range *list.definitions {
+		tokens := strings.SplitN(definition, ":", 2)
+		if len(tokens) != 2 || tokens[0] == "" {
+			log.Println("Ignoring invalid process group rule", definition)
+			continue
+		}
+		expression, err := regexp.Compile(tokens[1])
+		if err != nil {
+			log.Println("Ignoring invalid process group rule", definition, "-", err)
+			continue
+		}
+		list.rules = append(list.rules, &processGroupRule{name: tokens[0], expression: expression})
+	}
+}
+
+func (list *processGroupRuleList) isEmpty() bool {
+	list.once.Do(list.compile)
+	return len(list.rules) == 0
+}
+
+func (list *processGroupRuleList) getGroup(comm, cmdline string) string {
+	list.once.Do(list.compile)
+	for _, rule := range list.rules {
+		if rule.expression.MatchString(comm) || rule.expression.MatchString(cmdline) {
+			return rule.name
+		}
+	}
+	return ""
+}
+
+/**
+ * Sum of the processes of a group, like process-exporter namedprocess
+ * metrics. Groups outlive the processes, which keeps dashboards stable
+ * when pids change on every deployment.
+ */
+type LinuxProcessGroupStats struct {
+	Name           string `json:"name" kind:"label"`
+	Processes      uint64 `json:"processes" kind:"gauge" unit:"count"`
+	Threads        uint64 `json:"threads" kind:"gauge" unit:"count"`
+	UserCpuUsage   uint64 `json:"user_cpu_usage" kind:"gauge" unit:"percent"`
+	SystemCpuUsage uint64 `json:"system_cpu_usage" kind:"gauge" unit:"percent"`
+	MemRssSize     uint64 `json:"mem_rss_size" kind:"gauge" unit:"bytes"`
+	MemSwapSize    uint64 `json:"mem_swap_size" kind:"gauge" unit:"bytes"`
+	// Only with smaps_rollup, unlike RSS the PSS of a group never exceeds the physical memory
+	MemPss                      uint64 `json:"mem_pss" kind:"gauge" unit:"bytes"`
+	MemUss                      uint64 `json:"mem_uss" kind:"gauge" unit:"bytes"`
+	IOReadBytes                 uint64 `json:"io_read_bytes" kind:"counter" unit:"bytes"`
+	IOWriteBytes                uint64 `json:"io_write_bytes" kind:"counter" unit:"bytes"`
+	FDUsed                      uint64 `json:"fd_used" kind:"gauge" unit:"count"`
+	VoluntaryContextSwitches    uint64 `json:"voluntary_contextswitches" kind:"counter" unit:"count"`
+	NonVoluntaryContextSwitches uint64 `json:"nonvoluntary_contextswitches" kind:"counter" unit:"count"`
+}
+
+func (groupStats *LinuxProcessGroupStats) add(process *LinuxProcessStats) {
+	groupStats.Processes++
+	groupStats.Threads += process.Threads
+	groupStats.UserCpuUsage += process.UserCpuUsage
+	groupStats.SystemCpuUsage += process.SystemCpuUsage
+	groupStats.MemRssSize += process.MemRssSize
+	groupStats.MemSwapSize += process.MemSwapSize
+	groupStats.MemPss += process.MemPss
+	groupStats.MemUss += process.MemUss
+	groupStats.IOReadBytes += process.IOReadBytes
+	groupStats.IOWriteBytes += process.IOWriteBytes
+	groupStats.FDUsed += process.FDUsed
+	groupStats.VoluntaryContextSwitches += process.VoluntaryContextSwitches
+	groupStats.NonVoluntaryContextSwitches += process.NonVoluntaryContextSwitches
+}
+
+/**
+ * The service of a process is its container name, or its systemd unit
+ * for processes running on the host
+ */
+func getProcessService(process *LinuxProcessStats) string {
+	if process.Container != nil && process.Container.Name != "" {
+		return process.Container.Name
+	}
+	if process.ContainerId != "" {
+		return ""
+	}
+	return process.SystemdUnit
+}
+
+func getProcessGroup(processStats *LinuxProcessStats) string {
+	if group := processGroupRules.getGroup(processStats.Comm, processStats.CmdLine); group != "" {
+		return group
+	}
+	switch ProcessGroupBy {
+	case "comm":
+		return processStats.Comm
+	case "user":
+		return processStats.User
+	case "service":
+		return getProcessService(processStats)
+	}
+	return ""
+}
+
+/**
+ * Aggregates every process of NewLinuxAllProcessesStats, before the
+ * process filters and the top-N selection. Processes without a group
+ * are not counted anywhere.
+ */
+func NewLinuxProcessGroupsStats(processes []*LinuxProcessStats) []*LinuxProcessGroupStats {
+	if ProcessGroupBy == "" && processGroupRules.isEmpty() {
+		return nil
+	}
+
+	groups := make(map[string]*LinuxProcessGroupStats)
+	for _, process := range processes {
+		name := getProcessGroup(process)
+		if name == "" {
+			continue
+		}
+		group, present := groups[name]
+		if !present {
+			group = &LinuxProcessGroupStats{Name: name}
+			groups[name] = group
+		}
+		group.add(process)
+	}
+
+	groupsStats := []*LinuxProcessGroupStats{}
+	for _, group := range groups {
+		groupsStats = append(groupsStats, group)
+	}
+	sort.Slice(groupsStats, func(i, j int) bool {
+		return groupsStats[i].Name < groupsStats[j].Name
+	})
+	return groupsStats
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"os"
+	"time"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+// USER_HZ, the unit of the /proc/<pid>/stat times on every architecture
+const clockTicksPerSecond uint64 = 100
+
+// sched(7) policies, by the value in /proc/<pid>/stat
+var schedulingPolicies = map[uint64]string{
+	0: "normal",
+	1: "fifo",
+	2: "rr",
+	3: "batch",
+	5: "idle",
+	6: "deadline",
+}
+
+/**
+ * Links of /proc/<pid>, empty when the process has exited or belongs to
+ * another user and the collector lacks CAP_SYS_PTRACE
+ */
+type processPaths struct {
+	exe string
+	cwd string
+}
+
+func readProcessPaths(processPath string) *processPaths {
+	paths := processPaths{}
+	paths.exe, _ = os.Readlink(processPath + "/exe")
+	paths.cwd, _ = os.Readlink(processPath + "/cwd")
+	return &paths
+}
+
+func getSchedulingPolicy(policy uint64) string {
+	if name, present := schedulingPolicies[policy]; present {
+		return name
+	}
+	return "unknown"
+}
+
+/**
+ * Processes that rewrite their argv to an empty string, like kernel
+ * threads, are shown by their comm between brackets as ps(1) does
+ */
+func getProcessCmdline(process *linuxproc.Process) string {
+	if process.Cmdline != "" {
+		return process.Cmdline
+	}
+	return "[" + process.Status.Name + "]"
+}
+
+// Start time in milliseconds since the epoch, from the ticks since boot
+func getProcessStartTime(bootTime time.Time, process *linuxproc.Process) uint64 {
+	bootMilliseconds := uint64(bootTime.UnixNano()) / uint64(time.Millisecond)
+	return bootMilliseconds + process.Stat.Starttime*1000/clockTicksPerSecond
+}
@@ -0,0 +1,73 @@
+package stats
+
+import (
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+// PF_KTHREAD of the flags field of /proc/<pid>/stat
+const pfKthread uint64 = 0x00200000
+
+/**
+ * Same as linuxproc.ReadProcess, except that an unreadable io file does
+ * not discard the process. /proc/<pid>/io needs the rights to ptrace
+ * the process, the collector sees other users processes without
+ * CAP_SYS_PTRACE but not their I/O. The second value is false then.
+ */
+func readProcess(processPath string) (*linuxproc.Process, bool, error) {
+	process := linuxproc.Process{}
+
+	stat, err := linuxproc.ReadProcessStat(processPath + "/stat")
+	if err != nil {
+		return nil, false, err
+	}
+	statm, err := linuxproc.ReadProcessStatm(processPath + "/statm")
+	if err != nil {
+		return nil, false, err
+	}
+	status, err := linuxproc.ReadProcessStatus(processPath + "/status")
+	if err != nil {
+		return nil, false, err
+	}
+	cmdline, err := linuxproc.ReadProcessCmdline(processPath + "/cmdline")
+	if err != nil {
+		return nil, false, err
+	}
+	process.Stat = *stat
+	process.Statm = *statm
+	process.Status = *status
+	process.Cmdline = cmdline
+
+	io, err := linuxproc.ReadProcessIO(processPath + "/io")
+	if err != nil {
+		return &process, false, nil
+	}
+	process.IO = *io
+	return &process, true, nil
+}
+
+/**
+ * Rates of /proc/<pid>/io. The char counters include page cache hits,
+ * the bytes counters only what reached the block layer, and cancelled
+ * writes are dirty pages truncated before writeback.
+ */
+type LinuxProcessIOStats struct {
+	ReadCharsRate           float64 `json:"read_chars_rate" kind:"counter" unit:"bytes_per_second"`
+	WriteCharsRate          float64 `json:"write_chars_rate" kind:"counter" unit:"bytes_per_second"`
+	ReadSyscallsRate        float64 `json:"read_syscalls_rate" kind:"counter" unit:"per_second"`
+	WriteSyscallsRate       float64 `json:"write_syscalls_rate" kind:"counter" unit:"per_second"`
+	ReadBytesRate           float64 `json:"read_bytes_rate" kind:"counter" unit:"bytes_per_second"`
+	WriteBytesRate          float64 `json:"write_bytes_rate" kind:"counter" unit:"bytes_per_second"`
+	CancelledWriteBytesRate float64 `json:"cancelled_write_bytes_rate" kind:"counter" unit:"bytes_per_second"`
+}
+
+func newLinuxProcessIOStats(prev, curr *linuxproc.ProcessIO, seconds float64) *LinuxProcessIOStats {
+	ioStats := LinuxProcessIOStats{}
+	ioStats.ReadCharsRate = getCounterRate(prev.RChar, curr.RChar, seconds)
+	ioStats.WriteCharsRate = getCounterRate(prev.WChar, curr.WChar, seconds)
+	ioStats.ReadSyscallsRate = getCounterRate(prev.Syscr, curr.Syscr, seconds)
+	ioStats.WriteSyscallsRate = getCounterRate(prev.Syscw, curr.Syscw, seconds)
+	ioStats.ReadBytesRate = getCounterRate(prev.ReadBytes, curr.ReadBytes, seconds)
+	ioStats.WriteBytesRate = getCounterRate(prev.WriteBytes, curr.WriteBytes, seconds)
+	ioStats.CancelledWriteBytesRate = getCounterRate(prev.CancelledWriteBytes, curr.CancelledWriteBytes, seconds)
+	return &ioStats
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+var (
+	// Reads /proc/<pid>/smaps_rollup, which walks the page tables of the process
+	ProcessSmapsEnabled bool = false
+	// Seconds a smaps_rollup reading of a process is reused before reading it again
+	ProcessSmapsInterval int = 60
+
+	// statm sizes are in pages
+	pageSize uint64 = uint64(os.Getpagesize())
+	// Last readings by pid, only used by the sampling goroutine
+	processMemoryCache = make(map[uint64]*processMemory)
+)
+
+/**
+ * From /proc/<pid>/smaps_rollup (Linux 4.14+), converted to bytes:
+ *
+ *   Pss:                 479 kB
+ *   Shared_Clean:       1260 kB
+ *   Private_Dirty:       100 kB
+ *   SwapPss:               0 kB
+ */
+type processMemory struct {
+	pss          uint64
+	sharedClean  uint64
+	sharedDirty  uint64
+	privateClean uint64
+	privateDirty uint64
+	anonymous    uint64
+	swapPss      uint64
+	// Tell a reused pid and an outdated reading apart
+	startTime uint64
+	readTime  time.Time
+}
+
+func parseProcessMemory(data string) *processMemory {
+	memory := processMemory{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != "kB" {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		value = value * 1024
+		switch fields[0] {
+		case "Pss:":
+			memory.pss = value
+		case "Shared_Clean:":
+			memory.sharedClean = value
+		case "Shared_Dirty:":
+			memory.sharedDirty = value
+		case "Private_Clean:":
+			memory.privateClean = value
+		case "Private_Dirty:":
+			memory.privateDirty = value
+		case "Anonymous:":
+			memory.anonymous = value
+		case "SwapPss:":
+			memory.swapPss = value
+		}
+	}
+	return &memory
+}
+
+/**
+ * Readings of the sampled processes, at most one smaps_rollup read per
+ * process every ProcessSmapsInterval seconds. Processes that could not
+ * be read, for lack of CAP_SYS_PTRACE or kernel support, are missing.
+ */
+func readProcessesMemory(processes []*linuxproc.Process) map[uint64]*processMemory {
+	now := time.Now()
+	maxAge := time.Duration(ProcessSmapsInterval) * time.Second
+
+	memories := make(map[uint64]*processMemory)
+	for _, process := range processes {
+		pid := process.Status.Pid
+		memory, present := processMemoryCache[pid]
+		if !present || memory.startTime != process.Stat.Starttime || now.Sub(memory.readTime) >= maxAge {
+			data, err := ioutil.ReadFile(ProcPath + strconv.FormatUint(pid, 10) + "/smaps_rollup")
+			if err != nil {
+				continue
+			}
+			memory = parseProcessMemory(string(data))
+			memory.startTime = process.Stat.Starttime
+			memory.readTime = now
+		}
+		memories[pid] = memory
+	}
+	// Exited processes are dropped with the previous map
+	processMemoryCache = memories
+	return memories
+}
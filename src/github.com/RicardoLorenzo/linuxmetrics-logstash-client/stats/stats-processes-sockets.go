@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+/**
+ * Resolves the descriptors of every process to find its sockets, one
+ * readlink per open descriptor
+ */
+var ProcessSocketsEnabled bool = false
+
+// Inodes of the "socket:[<inode>]" links of /proc/<pid>/fd
+func readSocketInodes(fdPath string, names []string) []uint64 {
+	inodes := []uint64{}
+	for _, name := range names {
+		link, err := os.Readlink(fdPath + "/" + name)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(link[8:], "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes
+}
+
+func getTCPSocketsByInode(sockets []*linuxproc.NetTCPSocket) map[uint64]*linuxproc.NetSocket {
+	socketsByInode := make(map[uint64]*linuxproc.NetSocket)
+	for _, socket := range sockets {
+		// Sockets in TIME_WAIT and new_syn_recv have no inode
+		if socket.Inode != 0 {
+			socketsByInode[socket.Inode] = &socket.NetSocket
+		}
+	}
+	return socketsByInode
+}
+
+/**
+ * TCP sockets owned by a process. Only sockets of the network namespace
+ * of the collector are known, processes of containers with their own
+ * namespace have none.
+ */
+type LinuxProcessNetworkStats struct {
+	TCPConnections uint64            `json:"tcp_connections" kind:"gauge" unit:"count"`
+	TCPStates      map[string]uint64 `json:"tcp_states" kind:"gauge" unit:"count"`
+	ListenPorts    []uint64          `json:"listen_ports" kind:"label"`
+	TCPRxQueue     uint64            `json:"tcp_rx_queue" kind:"gauge" unit:"bytes"`
+	TCPTxQueue     uint64            `json:"tcp_tx_queue" kind:"gauge" unit:"bytes"`
+}
+
+/**
+ * Returns nil when the process has no TCP socket, a socket shared
+ * between a parent and its children is counted for each of them
+ */
+func newLinuxProcessNetworkStats(socketsByInode map[uint64]*linuxproc.NetSocket, files *processFiles) *LinuxProcessNetworkStats {
+	if files == nil || len(files.socketInodes) == 0 {
+		return nil
+	}
+
+	networkStats := LinuxProcessNetworkStats{}
+	networkStats.TCPStates = make(map[string]uint64)
+	socketStats := LinuxTCPSocketStats{}
+	for _, inode := range files.socketInodes {
+		socket, present := socketsByInode[inode]
+		if !present {
+			// UDP, Unix or a socket of another network namespace
+			continue
+		}
+		networkStats.TCPConnections++
+		networkStats.TCPStates[socketStats.getStateName(socket.Status)]++
+		networkStats.TCPRxQueue += socket.RxQueue
+		networkStats.TCPTxQueue += socket.TxQueue
+		if socket.Status == tcpStateListen {
+			if port, err := socketStats.getPort(socket.LocalAddress); err == nil && !containsPort(networkStats.ListenPorts, port) {
+				networkStats.ListenPorts = append(networkStats.ListenPorts, port)
+			}
+		}
+	}
+	if networkStats.TCPConnections == 0 {
+		return nil
+	}
+	sort.Slice(networkStats.ListenPorts, func(i, j int) bool {
+		return networkStats.ListenPorts[i] < networkStats.ListenPorts[j]
+	})
+	return &networkStats
+}
+
+// IPv4 and IPv6 listeners of a port are reported once
+func containsPort(ports []uint64, port uint64) bool {
+	for _, item := range ports {
+		if item == port {
+			return true
+		}
+	}
+	return false
+}
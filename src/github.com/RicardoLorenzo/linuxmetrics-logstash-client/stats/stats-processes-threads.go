@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+var (
+	// Regular expressions on the comm or the cmdline of the processes whose
+	// threads are reported, empty disables the thread breakdown
+	ProcessThreadsInclude = []string{}
+	// Busiest threads reported per process
+	ProcessThreadsTopCount int = 10
+
+	processThreadsInclude = newPatternList(&ProcessThreadsInclude)
+)
+
+type threadSample struct {
+	stat   *linuxproc.ProcessStat
+	status *linuxproc.ProcessStatus
+}
+
+func isThreadsProcess(process *linuxproc.Process) bool {
+	return processThreadsInclude.matches(process.Status.Name) ||
+		processThreadsInclude.matches(getProcessCmdline(process))
+}
+
+/**
+ * Threads of a process by tid, from /proc/<pid>/task/<tid>/stat and
+ * status. Threads exiting while they are read are skipped.
+ */
+func readProcessThreads(processPath string) map[uint64]*threadSample {
+	threads := make(map[uint64]*threadSample)
+
+	directory, err := os.Open(processPath + "/task")
+	if err != nil {
+		return threads
+	}
+	defer directory.Close()
+
+	names, err := directory.Readdirnames(-1)
+	if err != nil {
+		return threads
+	}
+	for _, name := range names {
+		tid, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		taskPath := processPath + "/task/" + name
+		stat, err := linuxproc.ReadProcessStat(taskPath + "/stat")
+		if err != nil {
+			continue
+		}
+		status, err := linuxproc.ReadProcessStatus(taskPath + "/status")
+		if err != nil {
+			continue
+		}
+		threads[tid] = &threadSample{stat: stat, status: status}
+	}
+	return threads
+}
+
+type LinuxThreadStats struct {
+	Tid                         uint64 `json:"tid" kind:"label"`
+	Name                        string `json:"name" kind:"label"`
+	State                       string `json:"state" kind:"label"`
+	UserCpuUsage                uint64 `json:"user_cpu_usage" kind:"gauge" unit:"percent"`
+	SystemCpuUsage              uint64 `json:"system_cpu_usage" kind:"gauge" unit:"percent"`
+	VoluntaryContextSwitches    uint64 `json:"voluntary_contextswitches" kind:"counter" unit:"count"`
+	NonVoluntaryContextSwitches uint64 `json:"nonvoluntary_contextswitches" kind:"counter" unit:"count"`
+}
+
+/**
+ * The ProcessThreadsTopCount threads with the highest CPU usage over the
+ * interval, nil for processes whose threads are not read
+ */
+func newLinuxThreadsStats(previous, current StatsSample, pid uint64) []*LinuxThreadStats {
+	currThreads, present := current.processThreads[pid]
+	if !present {
+		return nil
+	}
+	prevThreads := previous.processThreads[pid]
+	totalJiffies := (&LinuxProcessStats{}).getProcessTotalJiffies(previous, current)
+
+	threadsStats := []*LinuxThreadStats{}
+	for tid, curr := range currThreads {
+		prev, present := prevThreads[tid]
+		if !present || prev.stat.Starttime != curr.stat.Starttime {
+			prev = curr
+		}
+		threadStats := LinuxThreadStats{}
+		threadStats.Tid = tid
+		threadStats.Name = curr.status.Name
+		threadStats.State = curr.status.State
+		if totalJiffies > 0 {
+			threadStats.UserCpuUsage = uint64(math.Ceil(100.0 * float64(getCounterDelta(prev.stat.Utime, curr.stat.Utime)) / totalJiffies))
+			threadStats.SystemCpuUsage = uint64(math.Ceil(100.0 * float64(getCounterDelta(prev.stat.Stime, curr.stat.Stime)) / totalJiffies))
+		}
+		threadStats.VoluntaryContextSwitches = getCounterDelta(prev.status.VoluntaryCtxtSwitches, curr.status.VoluntaryCtxtSwitches)
+		threadStats.NonVoluntaryContextSwitches = getCounterDelta(prev.status.NonvoluntaryCtxtSwitches, curr.status.NonvoluntaryCtxtSwitches)
+		threadsStats = append(threadsStats, &threadStats)
+	}
+
+	sort.Slice(threadsStats, func(i, j int) bool {
+		usageI := threadsStats[i].UserCpuUsage + threadsStats[i].SystemCpuUsage
+		usageJ := threadsStats[j].UserCpuUsage + threadsStats[j].SystemCpuUsage
+		if usageI != usageJ {
+			return usageI > usageJ
+		}
+		return threadsStats[i].Tid < threadsStats[j].Tid
+	})
+	if ProcessThreadsTopCount > 0 && len(threadsStats) > ProcessThreadsTopCount {
+		threadsStats = threadsStats[:ProcessThreadsTopCount]
+	}
+	return threadsStats
+}
@@ -2,26 +2,82 @@ package stats
 
 import (
 	"math"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
 )
 
 type LinuxProcessStats struct {
-	CmdLine string `json:"cmdline"`
-	Pid uint64 `json:"pid"`
-	State string `json:"state"`
-	MemVirtSize uint64 `json:"mem_virtual_size"`
-	MemRssSize uint64 `json:"mem_rss_size"`
-	MemLockSize uint64 `json:"mem_lock_size"`
-	MemSwapSize uint64 `json:"mem_swap_size"`
-	Threads uint64 `json:"threads"`
-	FDUsed uint64 `json:"fd_used"`
-	SignalsIgnored uint64 `json:"sig_ignored"`
-	SignalsCaught uint64 `json:"sig_caught"`
-	VoluntaryContextSwitches uint64 `json:"voluntary_contextswitches"`
-	NonVoluntaryContextSwitches uint64 `json:"nonvoluntary_contextswitches"`
-	IOReadBytes uint64 `json:"io_read_bytes"`
-	IOWriteBytes uint64 `json:"io_write_bytes"`
-	UserCpuUsage uint64 `json:"user_cpu_usage"`
-	SystemCpuUsage uint64 `json:"system_cpu_usage"`
+	CmdLine string `json:"cmdline" kind:"label"`
+	Pid uint64 `json:"pid" kind:"label"`
+	State string `json:"state" kind:"label"`
+	Comm string `json:"comm" kind:"label"`
+	Ppid int64 `json:"ppid" kind:"label"`
+	Uid uint64 `json:"uid" kind:"label"`
+	Gid uint64 `json:"gid" kind:"label"`
+	// Name of the real uid, or the uid when the host has no such user
+	User string `json:"user" kind:"label"`
+	// Milliseconds since the epoch
+	StartTime uint64 `json:"start_time" kind:"label"`
+	Age uint64 `json:"age" kind:"gauge" unit:"seconds"`
+	Exe string `json:"exe" kind:"label"`
+	Cwd string `json:"cwd" kind:"label"`
+	Nice int64 `json:"nice" kind:"label"`
+	Priority int64 `json:"priority" kind:"label"`
+	SchedulingPolicy string `json:"scheduling_policy" kind:"label"`
+	// CPU the process last ran on
+	Processor int64 `json:"processor" kind:"label"`
+	MemVirtSize uint64 `json:"mem_virtual_size" kind:"gauge" unit:"bytes"`
+	MemRssSize uint64 `json:"mem_rss_size" kind:"gauge" unit:"bytes"`
+	MemLockSize uint64 `json:"mem_lock_size" kind:"gauge" unit:"bytes"`
+	MemSwapSize uint64 `json:"mem_swap_size" kind:"gauge" unit:"bytes"`
+	// From smaps_rollup when enabled, USS is the private memory freed on exit
+	MemPss uint64 `json:"mem_pss" kind:"gauge" unit:"bytes"`
+	MemUss uint64 `json:"mem_uss" kind:"gauge" unit:"bytes"`
+	MemSharedClean uint64 `json:"mem_shared_clean" kind:"gauge" unit:"bytes"`
+	MemSharedDirty uint64 `json:"mem_shared_dirty" kind:"gauge" unit:"bytes"`
+	MemPrivateClean uint64 `json:"mem_private_clean" kind:"gauge" unit:"bytes"`
+	MemPrivateDirty uint64 `json:"mem_private_dirty" kind:"gauge" unit:"bytes"`
+	MemAnonymous uint64 `json:"mem_anonymous" kind:"gauge" unit:"bytes"`
+	MemSwapPss uint64 `json:"mem_swap_pss" kind:"gauge" unit:"bytes"`
+	Threads uint64 `json:"threads" kind:"gauge" unit:"count"`
+	// Open descriptors and resource limits, 0 when unknown or unlimited
+	FDUsed uint64 `json:"fd_used" kind:"gauge" unit:"count"`
+	FDLimitSoft uint64 `json:"fd_limit_soft" kind:"gauge" unit:"count"`
+	FDLimitHard uint64 `json:"fd_limit_hard" kind:"gauge" unit:"count"`
+	FDUsedPercentage float64 `json:"fd_used_percentage" kind:"gauge" unit:"percent"`
+	ProcessesLimitSoft uint64 `json:"processes_limit_soft" kind:"gauge" unit:"count"`
+	ProcessesLimitHard uint64 `json:"processes_limit_hard" kind:"gauge" unit:"count"`
+	MemLockLimitSoft uint64 `json:"mem_lock_limit_soft" kind:"gauge" unit:"bytes"`
+	MemLockLimitHard uint64 `json:"mem_lock_limit_hard" kind:"gauge" unit:"bytes"`
+	MemLockUsedPercentage float64 `json:"mem_lock_used_percentage" kind:"gauge" unit:"percent"`
+	StackLimitSoft uint64 `json:"stack_limit_soft" kind:"gauge" unit:"bytes"`
+	StackLimitHard uint64 `json:"stack_limit_hard" kind:"gauge" unit:"bytes"`
+	StackUsedPercentage float64 `json:"stack_used_percentage" kind:"gauge" unit:"percent"`
+	SignalsIgnored uint64 `json:"sig_ignored" kind:"counter" unit:"count"`
+	SignalsCaught uint64 `json:"sig_caught" kind:"counter" unit:"count"`
+	VoluntaryContextSwitches uint64 `json:"voluntary_contextswitches" kind:"counter" unit:"count"`
+	NonVoluntaryContextSwitches uint64 `json:"nonvoluntary_contextswitches" kind:"counter" unit:"count"`
+	IOReadBytes uint64 `json:"io_read_bytes" kind:"counter" unit:"bytes"`
+	IOWriteBytes uint64 `json:"io_write_bytes" kind:"counter" unit:"bytes"`
+	// /proc/<pid>/io is not readable, the I/O fields are unknown rather than zero
+	IODenied bool `json:"io_denied" kind:"label"`
+	IO *LinuxProcessIOStats `json:"io"`
+	UserCpuUsage uint64 `json:"user_cpu_usage" kind:"gauge" unit:"percent"`
+	SystemCpuUsage uint64 `json:"system_cpu_usage" kind:"gauge" unit:"percent"`
+	// Only for processes matching processes.schedstat.include
+	Schedstat *LinuxSchedStats `json:"schedstat"`
+	// From /proc/<pid>/cgroup
+	Cgroup string `json:"cgroup" kind:"label"`
+	ContainerId string `json:"container_id" kind:"label"`
+	PodUid string `json:"pod_uid" kind:"label"`
+	QosClass string `json:"qos_class" kind:"label"`
+	SystemdUnit string `json:"systemd_unit" kind:"label"`
+	SystemdSlice string `json:"systemd_slice" kind:"label"`
+	Container *ContainerMetadata `json:"container"`
+	// Only with processes.sockets.enabled
+	Network *LinuxProcessNetworkStats `json:"network"`
+	// Busiest threads, only for processes matching processes.threads.include
+	TopThreads []*LinuxThreadStats `json:"top_threads"`
 }
 
 func (processStats *LinuxProcessStats) getProcessTotalJiffies(prev, curr StatsSample) float64 {
@@ -37,10 +93,10 @@ func (processStats *LinuxProcessStats) getProcessTotalJiffies(prev, curr StatsSa
 	return float64(deltaTotal)
 }
 
-func (processStats *LinuxProcessStats) getProcessUsage(prev, curr StatsSample, index int) (uint64, uint64) {
+func (processStats *LinuxProcessStats) getProcessUsage(prev, curr StatsSample, prevProcess, currProcess *linuxproc.Process) (uint64, uint64) {
 	totalJiffies := processStats.getProcessTotalJiffies(prev, curr)
-	userJiffies := curr.processes[index].Stat.Utime - prev.processes[index].Stat.Utime
-	systemJiffies := curr.processes[index].Stat.Stime - prev.processes[index].Stat.Stime
+	userJiffies := getCounterDelta(prevProcess.Stat.Utime, currProcess.Stat.Utime)
+	systemJiffies := getCounterDelta(prevProcess.Stat.Stime, currProcess.Stat.Stime)
 
   percentageUser := 100.0 * float64(userJiffies) / totalJiffies
 	percentageSystem := 100.0 * float64(systemJiffies) / totalJiffies
@@ -54,32 +110,162 @@ func (processStats *LinuxProcessStats) capToLong(number uint64) uint64 {
   return number
 }
 
-func NewLinuxProcessesStats() []*LinuxProcessStats {
-	processes := []*LinuxProcessStats{}
+func (processStats *LinuxProcessStats) setCgroup(cgroup *processCgroup) {
+	if cgroup == nil {
+		return
+	}
+	processStats.Cgroup = cgroup.path
+	processStats.ContainerId = cgroup.containerId
+	processStats.PodUid = cgroup.podUid
+	processStats.QosClass = cgroup.qosClass
+	processStats.SystemdUnit = cgroup.systemdUnit
+	processStats.SystemdSlice = cgroup.systemdSlice
+	processStats.Container = getContainerMetadata(cgroup.containerId, cgroup.podUid)
+}
 
-	previous, current := SharedStatsPeriod.GetStatsSamples()
+/**
+ * Sample processes by pid. Pids are reused, the start time tells a new
+ * process apart from the one that had the pid before.
+ */
+func getProcessesByPid(sample StatsSample) map[uint64]*linuxproc.Process {
+	processes := make(map[uint64]*linuxproc.Process)
+	for _, process := range sample.processes {
+		processes[process.Status.Pid] = process
+	}
+	return processes
+}
 
-	for i, _ := range current.processes {
-		process := LinuxProcessStats{}
-		process.CmdLine = current.processes[i].Cmdline
-		process.Pid = current.processes[i].Status.Pid
-		process.State = current.processes[i].Status.State
-		process.MemVirtSize = current.processes[i].Statm.Size
-		process.MemRssSize = current.processes[i].Statm.Resident
-		process.MemLockSize = current.processes[i].Status.VmLck
-		process.MemSwapSize = current.processes[i].Status.VmSwap
-		process.Threads = current.processes[i].Status.Threads
-		process.FDUsed = current.processes[i].Status.FDSize
-		process.SignalsIgnored = process.capToLong(current.processes[i].Status.SigIgn - previous.processes[i].Status.SigIgn)
-		process.SignalsCaught = process.capToLong(current.processes[i].Status.SigCgt - previous.processes[i].Status.SigCgt)
-		process.UserCpuUsage, process.SystemCpuUsage = process.getProcessUsage(previous, current, i)
-		process.VoluntaryContextSwitches = process.capToLong(current.processes[i].Status.VoluntaryCtxtSwitches - previous.processes[i].Status.VoluntaryCtxtSwitches)
-		process.NonVoluntaryContextSwitches = process.capToLong(current.processes[i].Status.NonvoluntaryCtxtSwitches - previous.processes[i].Status.NonvoluntaryCtxtSwitches)
-		process.IOReadBytes = current.processes[i].IO.ReadBytes - previous.processes[i].IO.ReadBytes
-		process.IOWriteBytes = current.processes[i].IO.WriteBytes - previous.processes[i].IO.WriteBytes
-
-		processes = append(processes, &process)
+func newLinuxProcessStats(previous, current StatsSample, prevProcess, currProcess *linuxproc.Process) *LinuxProcessStats {
+	if prevProcess == nil || prevProcess.Stat.Starttime != currProcess.Stat.Starttime {
+		// Started during the interval, counters are reported from zero
+		prevProcess = currProcess
 	}
 
+	process := LinuxProcessStats{}
+	process.CmdLine = getProcessCmdline(currProcess)
+	process.Pid = currProcess.Status.Pid
+	process.State = currProcess.Status.State
+	process.setIdentity(current, currProcess)
+	process.MemVirtSize = currProcess.Statm.Size * pageSize
+	process.MemRssSize = currProcess.Statm.Resident * pageSize
+	process.MemLockSize = currProcess.Status.VmLck * 1024
+	process.MemSwapSize = currProcess.Status.VmSwap * 1024
+	process.setMemory(current.processMemory[process.Pid])
+	process.Threads = currProcess.Status.Threads
+	process.SignalsIgnored = process.capToLong(currProcess.Status.SigIgn - prevProcess.Status.SigIgn)
+	process.SignalsCaught = process.capToLong(currProcess.Status.SigCgt - prevProcess.Status.SigCgt)
+	process.UserCpuUsage, process.SystemCpuUsage = process.getProcessUsage(previous, current, prevProcess, currProcess)
+	if currSchedstat, present := current.processSchedstat[process.Pid]; present {
+		var prevSchedstat *linuxproc.ProcessSchedStat
+		if prevProcess != currProcess {
+			prevSchedstat = previous.processSchedstat[process.Pid]
+		}
+		process.Schedstat = newLinuxSchedStats(prevSchedstat, currSchedstat)
+	}
+	process.VoluntaryContextSwitches = process.capToLong(currProcess.Status.VoluntaryCtxtSwitches - prevProcess.Status.VoluntaryCtxtSwitches)
+	process.NonVoluntaryContextSwitches = process.capToLong(currProcess.Status.NonvoluntaryCtxtSwitches - prevProcess.Status.NonvoluntaryCtxtSwitches)
+	process.IODenied = current.processIODenied[process.Pid]
+	if !process.IODenied && !previous.processIODenied[process.Pid] {
+		process.IOReadBytes = getCounterDelta(prevProcess.IO.ReadBytes, currProcess.IO.ReadBytes)
+		process.IOWriteBytes = getCounterDelta(prevProcess.IO.WriteBytes, currProcess.IO.WriteBytes)
+		process.IO = newLinuxProcessIOStats(&prevProcess.IO, &currProcess.IO, current.getElapsedSeconds(previous))
+	}
+	process.setCgroup(current.processCgroups[process.Pid])
+	process.setFiles(current.processFiles[process.Pid], currProcess)
+	process.Network = newLinuxProcessNetworkStats(current.tcpSocketsByInode, current.processFiles[process.Pid])
+	process.TopThreads = newLinuxThreadsStats(previous, current, process.Pid)
+	return &process
+}
+
+// smaps_rollup readings, nil when disabled or not readable
+func (processStats *LinuxProcessStats) setMemory(memory *processMemory) {
+	if memory == nil {
+		return
+	}
+	processStats.MemPss = memory.pss
+	processStats.MemUss = memory.privateClean + memory.privateDirty
+	processStats.MemSharedClean = memory.sharedClean
+	processStats.MemSharedDirty = memory.sharedDirty
+	processStats.MemPrivateClean = memory.privateClean
+	processStats.MemPrivateDirty = memory.privateDirty
+	processStats.MemAnonymous = memory.anonymous
+	processStats.MemSwapPss = memory.swapPss
+}
+
+func (processStats *LinuxProcessStats) setIdentity(current StatsSample, process *linuxproc.Process) {
+	processStats.Comm = process.Status.Name
+	processStats.Ppid = process.Status.PPid
+	processStats.Uid = process.Status.RealUid
+	processStats.Gid = process.Status.RealGid
+	processStats.User = getUserName(current.users, process.Status.RealUid)
+	processStats.StartTime = getProcessStartTime(current.stat.BootTime, process)
+	if current.time > processStats.StartTime {
+		processStats.Age = (current.time - processStats.StartTime) / 1000
+	}
+	if paths, present := current.processPaths[processStats.Pid]; present {
+		processStats.Exe = paths.exe
+		processStats.Cwd = paths.cwd
+	}
+	processStats.Nice = process.Stat.Nice
+	processStats.Priority = process.Stat.Priority
+	processStats.SchedulingPolicy = getSchedulingPolicy(process.Stat.Policy)
+	processStats.Processor = process.Stat.Processor
+}
+
+/**
+ * Usage is compared with the soft limits, the ones that make open(2)
+ * fail with EMFILE or mlock(2) with ENOMEM
+ */
+func (processStats *LinuxProcessStats) setFiles(files *processFiles, process *linuxproc.Process) {
+	if files == nil {
+		return
+	}
+	if files.fds != nil {
+		processStats.FDUsed = *files.fds
+	}
+	if files.limits == nil {
+		return
+	}
+	limits := files.limits
+	processStats.FDLimitSoft = limits.openFiles.soft
+	processStats.FDLimitHard = limits.openFiles.hard
+	if files.fds != nil {
+		processStats.FDUsedPercentage = getLimitPercentage(*files.fds, limits.openFiles.soft)
+	}
+	processStats.ProcessesLimitSoft = limits.processes.soft
+	processStats.ProcessesLimitHard = limits.processes.hard
+	processStats.MemLockLimitSoft = limits.lockedMemory.soft
+	processStats.MemLockLimitHard = limits.lockedMemory.hard
+	processStats.MemLockUsedPercentage = getLimitPercentage(processStats.MemLockSize, limits.lockedMemory.soft)
+	processStats.StackLimitSoft = limits.stack.soft
+	processStats.StackLimitHard = limits.stack.hard
+	processStats.StackUsedPercentage = getLimitPercentage(process.Status.VmStk*1024, limits.stack.soft)
+}
+
+/**
+ * Statistics of every process of the current sample, shared by the
+ * process selection and the process groups
+ */
+func NewLinuxAllProcessesStats() []*LinuxProcessStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	previousProcesses := getProcessesByPid(previous)
+
+	processes := []*LinuxProcessStats{}
+	for _, currProcess := range current.processes {
+		processes = append(processes, newLinuxProcessStats(previous, current, previousProcesses[currProcess.Status.Pid], currProcess))
+	}
 	return processes
 }
+
+/**
+ * Returns the selected processes and how many were left out by the
+ * filters and the top-N selection
+ */
+func NewLinuxProcessesStats(processes []*LinuxProcessStats) ([]*LinuxProcessStats, uint64) {
+	selection := newProcessSelection()
+	for _, process := range processes {
+		selection.add(process)
+	}
+
+	return selection.getProcesses(), selection.omitted
+}
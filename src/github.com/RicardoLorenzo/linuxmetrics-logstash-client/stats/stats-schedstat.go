@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+var (
+	// Regular expressions on the comm or the cmdline of the processes whose
+	// /proc/<pid>/schedstat is reported, empty disables it
+	ProcessSchedstatInclude = []string{}
+
+	processSchedstatInclude = newPatternList(&ProcessSchedstatInclude)
+)
+
+/**
+ * Sum of the cpu<N> lines of /proc/schedstat, whose last three fields
+ * are the nanoseconds on the CPU, the nanoseconds runnable but waiting
+ * on a run queue and the timeslices run, like /proc/<pid>/schedstat.
+ * Kernels built without CONFIG_SCHEDSTATS have no such file, nil is
+ * returned then and for a malformed line.
+ *
+ * https://www.kernel.org/doc/html/latest/scheduler/sched-stats.html
+ */
+func readHostSchedstat(path string) *linuxproc.ProcessSchedStat {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var host *linuxproc.ProcessSchedStat
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		values := make([]uint64, 3)
+		for i, field := range fields[7:10] {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				log.Println("Cannot parse", path, "-", err)
+				return nil
+			}
+		}
+		if host == nil {
+			host = &linuxproc.ProcessSchedStat{}
+		}
+		host.RunTime += values[0]
+		host.RunqueueTime += values[1]
+		host.RunPeriods += values[2]
+	}
+	return host
+}
+
+func isSchedstatProcess(process *linuxproc.Process) bool {
+	return processSchedstatInclude.matches(process.Status.Name) ||
+		processSchedstatInclude.matches(getProcessCmdline(process))
+}
+
+/**
+ * A process can show a low CPU usage because it has little to do, or
+ * because it is starved. The wait ratio is the share of its runnable
+ * time spent on a run queue instead of a CPU.
+ */
+type LinuxSchedStats struct {
+	RunTime          float64 `json:"run_time" kind:"counter" unit:"ms"`
+	WaitTime         float64 `json:"wait_time" kind:"counter" unit:"ms"`
+	Timeslices       uint64  `json:"timeslices" kind:"counter" unit:"count"`
+	WaitRatio        float64 `json:"wait_ratio" kind:"gauge" unit:"percent"`
+	WaitPerTimeslice float64 `json:"wait_per_timeslice" kind:"gauge" unit:"ms"`
+}
+
+func newLinuxSchedStats(prev, curr *linuxproc.ProcessSchedStat) *LinuxSchedStats {
+	if curr == nil {
+		return nil
+	}
+	if prev == nil {
+		prev = curr
+	}
+	runTime := getCounterDelta(prev.RunTime, curr.RunTime)
+	waitTime := getCounterDelta(prev.RunqueueTime, curr.RunqueueTime)
+
+	schedStats := LinuxSchedStats{}
+	schedStats.RunTime = float64(runTime) / 1e6
+	schedStats.WaitTime = float64(waitTime) / 1e6
+	schedStats.Timeslices = getCounterDelta(prev.RunPeriods, curr.RunPeriods)
+	if runTime+waitTime > 0 {
+		schedStats.WaitRatio = 100.0 * float64(waitTime) / float64(runTime+waitTime)
+	}
+	if schedStats.Timeslices > 0 {
+		schedStats.WaitPerTimeslice = schedStats.WaitTime / float64(schedStats.Timeslices)
+	}
+	return &schedStats
+}
+
+func NewLinuxHostSchedStats() *LinuxSchedStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	return newLinuxSchedStats(previous.schedstat, current.schedstat)
+}
@@ -0,0 +1,173 @@
+package stats
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/**
+ * Every field sent to Logstash is described with two struct tags next
+ * to its json tag:
+ *
+ *   kind:"counter" the change of a kernel counter over the sample
+ *                  interval, or its per second rate
+ *   kind:"gauge"   an instantaneous value
+ *   kind:"label"   identifies the document (names, pids, states)
+ *
+ *   unit:"bytes"   unit of the value, optional for labels
+ *
+ * The catalog is built from those tags, so it is always in sync with
+ * what the binary actually sends.
+ */
+var schemaKinds = map[string]bool{
+	"counter": true,
+	"gauge":   true,
+	"label":   true,
+}
+
+type SchemaField struct {
+	// Value of the type field of the documents having the field
+	Document string `json:"document"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Unit     string `json:"unit,omitempty"`
+	Kind     string `json:"kind"`
+}
+
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "Invalid statistics schema:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+type schemaWalker struct {
+	document string
+	fields   []*SchemaField
+	problems []string
+	// Names of the struct types walked
+	types map[string]bool
+}
+
+func (walker *schemaWalker) getJSONName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+func (walker *schemaWalker) walk(prefix string, structType reflect.Type) {
+	if walker.types == nil {
+		walker.types = make(map[string]bool)
+	}
+	walker.types[structType.Name()] = true
+	names := make(map[string]string)
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			// Unexported fields are never encoded
+			continue
+		}
+
+		jsonName := walker.getJSONName(field)
+		if jsonName == "" || jsonName == "-" {
+			walker.problems = append(walker.problems,
+				fmt.Sprintf("%s.%s has no json tag", structType.Name(), field.Name))
+			continue
+		}
+		if other, present := names[jsonName]; present {
+			walker.problems = append(walker.problems,
+				fmt.Sprintf("%s.%s repeats json tag %q of %s", structType.Name(), field.Name, jsonName, other))
+			continue
+		}
+		names[jsonName] = field.Name
+
+		name := jsonName
+		if prefix != "" {
+			name = prefix + "." + jsonName
+		}
+		walker.walkField(name, structType.Name()+"."+field.Name, field, field.Type)
+	}
+}
+
+func (walker *schemaWalker) walkField(name, goName string, field reflect.StructField, fieldType reflect.Type) {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		walker.walk(name, fieldType)
+		return
+	case reflect.Slice, reflect.Array:
+		elemType := fieldType.Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct {
+			walker.walk(name+"[]", elemType)
+			return
+		}
+	case reflect.Map:
+		elemType := fieldType.Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct {
+			walker.walk(name+".*", elemType)
+			return
+		}
+		name = name + ".*"
+	}
+
+	kind := field.Tag.Get("kind")
+	if !schemaKinds[kind] {
+		walker.problems = append(walker.problems,
+			fmt.Sprintf("%s has no valid kind tag (counter, gauge or label)", goName))
+		return
+	}
+	if kind != "label" && field.Tag.Get("unit") == "" {
+		walker.problems = append(walker.problems,
+			fmt.Sprintf("%s has no unit tag", goName))
+		return
+	}
+
+	walker.fields = append(walker.fields, &SchemaField{
+		Document: walker.document,
+		Name:     name,
+		Type:     fieldType.String(),
+		Unit:     field.Tag.Get("unit"),
+		Kind:     kind,
+	})
+}
+
+// Walks the root type of every document sent
+func walkSchema() *schemaWalker {
+	walker := schemaWalker{}
+	walker.document = "osmetrics"
+	walker.walk("", reflect.TypeOf(JSONStats{}))
+	// process_started and process_exited share their fields
+	walker.document = ProcessStartedEvent + "," + ProcessExitedEvent
+	walker.walk("", reflect.TypeOf(LinuxProcessEvent{}))
+	walker.document = ProcessChurnEvent
+	walker.walk("", reflect.TypeOf(LinuxProcessChurnEvent{}))
+	return &walker
+}
+
+/**
+ * Field catalog of the documents sent to Logstash, walked from their
+ * root types: JSONStats for osmetrics, LinuxProcessEvent for the
+ * process lifecycle events and LinuxProcessChurnEvent for
+ * process_churn. Exported types of this package that no document
+ * embeds are not part of the catalog, a test fails on those carrying
+ * kind or unit tags. An error is returned when a field has a missing or
+ * duplicated json tag, since encoding/json silently drops those, or
+ * lacks its unit/kind tags.
+ */
+func GetSchema() ([]*SchemaField, error) {
+	walker := walkSchema()
+	if len(walker.problems) > 0 {
+		return walker.fields, &SchemaError{walker.problems}
+	}
+	return walker.fields, nil
+}
@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Golden file written by "make schema", next to the main package
+const schemaGoldenPath = "../schema.json"
+
+func TestSchemaMatchesGoldenFile(t *testing.T) {
+	schema, err := GetSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := ioutil.ReadFile(schemaGoldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(golden)) != string(catalog) {
+		t.Errorf("%s is out of date, run \"make schema\" after an intended schema change", schemaGoldenPath)
+	}
+}
+
+// Exported structs of the package sources having kind or unit tags
+func getTaggedStructs(t *testing.T) []string {
+	fileSet := token.NewFileSet()
+	notTest := func(info os.FileInfo) bool { return !strings.HasSuffix(info.Name(), "_test.go") }
+	packages, err := parser.ParseDir(fileSet, ".", notTest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, file := range packages["stats"].Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok || !spec.Name.IsExported() {
+				return true
+			}
+			structType, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range structType.Fields.List {
+				if field.Tag != nil && (strings.Contains(field.Tag.Value, `kind:"`) ||
+					strings.Contains(field.Tag.Value, `unit:"`)) {
+					names = append(names, spec.Name.Name)
+					break
+				}
+			}
+			return true
+		})
+	}
+	return names
+}
+
+func TestSchemaReachesTaggedStructs(t *testing.T) {
+	walker := walkSchema()
+	names := getTaggedStructs(t)
+	if len(names) == 0 {
+		t.Fatal("no tagged struct found in the package sources")
+	}
+	for _, name := range names {
+		if !walker.types[name] {
+			t.Errorf("%s has kind/unit tags but no document sent embeds it, wire it into a root of walkSchema", name)
+		}
+	}
+}
+
+type schemaTestNested struct {
+	Value uint64 `json:"value" kind:"gauge" unit:"count"`
+}
+
+type schemaTestStats struct {
+	Name     string              `json:"name" kind:"label"`
+	Count    uint64              `json:"count" kind:"counter" unit:"count"`
+	Nested   *schemaTestNested   `json:"nested"`
+	Items    []*schemaTestNested `json:"items"`
+	hidden   uint64
+	Untagged uint64
+	NoKind   uint64 `json:"no_kind" unit:"count"`
+	NoUnit   uint64 `json:"no_unit" kind:"gauge"`
+}
+
+func TestSchemaWalker(t *testing.T) {
+	walker := schemaWalker{document: "test"}
+	walker.walk("", reflect.TypeOf(schemaTestStats{}))
+
+	names := []string{}
+	for _, field := range walker.fields {
+		if field.Document != "test" {
+			t.Errorf("%s has document %q", field.Name, field.Document)
+		}
+		names = append(names, field.Name)
+	}
+	expected := []string{"name", "count", "nested.value", "items[].value"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("fields %v, expected %v", names, expected)
+	}
+
+	problems := []string{
+		"schemaTestStats.Untagged has no json tag",
+		"schemaTestStats.NoKind has no valid kind tag (counter, gauge or label)",
+		"schemaTestStats.NoUnit has no unit tag",
+	}
+	if !reflect.DeepEqual(walker.problems, problems) {
+		t.Errorf("problems %q, expected %q", walker.problems, problems)
+	}
+}
+
+func TestSchemaWalkerRepeatedTag(t *testing.T) {
+	// go vet rejects a literal struct repeating a json tag
+	tag := reflect.StructTag(`json:"count" kind:"counter" unit:"count"`)
+	repeated := reflect.StructOf([]reflect.StructField{
+		{Name: "Count", Type: reflect.TypeOf(uint64(0)), Tag: tag},
+		{Name: "Repeated", Type: reflect.TypeOf(uint64(0)), Tag: tag},
+	})
+
+	walker := schemaWalker{}
+	walker.walk("", repeated)
+	if len(walker.fields) != 1 || len(walker.problems) != 1 ||
+		!strings.Contains(walker.problems[0], "Repeated repeats json tag \"count\" of Count") {
+		t.Errorf("fields %v, problems %q", walker.fields, walker.problems)
+	}
+}
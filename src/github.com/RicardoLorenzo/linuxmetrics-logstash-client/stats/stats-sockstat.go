@@ -0,0 +1,151 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+/**
+ * Socket counters of /proc/net/sockstat and sockstat6, with the limits
+ * they are checked against:
+ *
+ *   TCP: inuse 4 orphan 0 tw 0 alloc 4 mem 1
+ *
+ * tcp_mem and udp_mem hold min, pressure and max, in pages like mem.
+ */
+type sockstatSample struct {
+	sockstat *linuxproc.SockStat
+	tcpMem   []uint64
+	udpMem   []uint64
+	// net.ipv4.tcp_max_orphans and tcp_max_tw_buckets
+	maxOrphans  uint64
+	maxTimeWait uint64
+}
+
+func readSysctlValues(path string) []uint64 {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	values := []uint64{}
+	for _, field := range strings.Fields(string(data)) {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// 0 when the file is missing or holds something else than one number
+func readSysctlValue(path string) uint64 {
+	values := readSysctlValues(path)
+	if len(values) != 1 {
+		return 0
+	}
+	return values[0]
+}
+
+func readSockstat(procPath string) (*sockstatSample, error) {
+	sockstat, err := linuxproc.ReadSockStat(procPath + "net/sockstat")
+	if err != nil {
+		return nil, err
+	}
+
+	// No sockstat6 when IPv6 is disabled on the host
+	sockstat6, err := linuxproc.ReadSockStat(procPath + "net/sockstat6")
+	if err == nil {
+		sockstat.TCP6InUse = sockstat6.TCP6InUse
+		sockstat.UDP6InUse = sockstat6.UDP6InUse
+		sockstat.UDPLITE6InUse = sockstat6.UDPLITE6InUse
+		sockstat.RAW6InUse = sockstat6.RAW6InUse
+		sockstat.FRAG6InUse = sockstat6.FRAG6InUse
+		sockstat.FRAG6Memory = sockstat6.FRAG6Memory
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sample := sockstatSample{sockstat: sockstat}
+	sample.tcpMem = readSysctlValues(procPath + "sys/net/ipv4/tcp_mem")
+	sample.udpMem = readSysctlValues(procPath + "sys/net/ipv4/udp_mem")
+	sample.maxOrphans = readSysctlValue(procPath + "sys/net/ipv4/tcp_max_orphans")
+	sample.maxTimeWait = readSysctlValue(procPath + "sys/net/ipv4/tcp_max_tw_buckets")
+	return &sample, nil
+}
+
+/**
+ * Sockets in use and the memory held by their buffers. Above the
+ * pressure threshold of tcp_mem the kernel shrinks socket buffers, at
+ * the max it drops packets and logs "out of memory -- consider tuning
+ * tcp_mem". Orphans and TIME_WAIT sockets beyond their limits are reset.
+ */
+type LinuxSockstatStats struct {
+	SocketsUsed               uint64  `json:"sockets_used" kind:"gauge" unit:"count"`
+	TCPInUse                  uint64  `json:"tcp_inuse" kind:"gauge" unit:"count"`
+	TCP6InUse                 uint64  `json:"tcp6_inuse" kind:"gauge" unit:"count"`
+	TCPAllocated              uint64  `json:"tcp_allocated" kind:"gauge" unit:"count"`
+	TCPOrphan                 uint64  `json:"tcp_orphan" kind:"gauge" unit:"count"`
+	TCPOrphanMax              uint64  `json:"tcp_orphan_max" kind:"gauge" unit:"count"`
+	TCPOrphanUsedPercentage   float64 `json:"tcp_orphan_used_percentage" kind:"gauge" unit:"percent"`
+	TCPTimeWait               uint64  `json:"tcp_time_wait" kind:"gauge" unit:"count"`
+	TCPTimeWaitMax            uint64  `json:"tcp_time_wait_max" kind:"gauge" unit:"count"`
+	TCPTimeWaitUsedPercentage float64 `json:"tcp_time_wait_used_percentage" kind:"gauge" unit:"percent"`
+	TCPMemory                 uint64  `json:"tcp_memory" kind:"gauge" unit:"pages"`
+	TCPMemoryPressure         uint64  `json:"tcp_memory_pressure" kind:"gauge" unit:"pages"`
+	TCPMemoryMax              uint64  `json:"tcp_memory_max" kind:"gauge" unit:"pages"`
+	TCPMemoryUsedPercentage   float64 `json:"tcp_memory_used_percentage" kind:"gauge" unit:"percent"`
+	UDPInUse                  uint64  `json:"udp_inuse" kind:"gauge" unit:"count"`
+	UDP6InUse                 uint64  `json:"udp6_inuse" kind:"gauge" unit:"count"`
+	UDPMemory                 uint64  `json:"udp_memory" kind:"gauge" unit:"pages"`
+	UDPMemoryPressure         uint64  `json:"udp_memory_pressure" kind:"gauge" unit:"pages"`
+	UDPMemoryMax              uint64  `json:"udp_memory_max" kind:"gauge" unit:"pages"`
+	UDPMemoryUsedPercentage   float64 `json:"udp_memory_used_percentage" kind:"gauge" unit:"percent"`
+	RawInUse                  uint64  `json:"raw_inuse" kind:"gauge" unit:"count"`
+	Raw6InUse                 uint64  `json:"raw6_inuse" kind:"gauge" unit:"count"`
+	FragMemory                uint64  `json:"frag_memory" kind:"gauge" unit:"bytes"`
+	Frag6Memory               uint64  `json:"frag6_memory" kind:"gauge" unit:"bytes"`
+}
+
+func NewLinuxSockstatStats() *LinuxSockstatStats {
+	_, current := SharedStatsPeriod.GetStatsSamples()
+	if current.sockstat == nil {
+		return nil
+	}
+	sockstat := current.sockstat.sockstat
+
+	sockstatStats := LinuxSockstatStats{}
+	sockstatStats.SocketsUsed = sockstat.SocketsUsed
+	sockstatStats.TCPInUse = sockstat.TCPInUse
+	sockstatStats.TCP6InUse = sockstat.TCP6InUse
+	sockstatStats.TCPAllocated = sockstat.TCPAllocated
+	sockstatStats.TCPOrphan = sockstat.TCPOrphan
+	sockstatStats.TCPOrphanMax = current.sockstat.maxOrphans
+	sockstatStats.TCPOrphanUsedPercentage = getLimitPercentage(sockstat.TCPOrphan, current.sockstat.maxOrphans)
+	sockstatStats.TCPTimeWait = sockstat.TCPTimeWait
+	sockstatStats.TCPTimeWaitMax = current.sockstat.maxTimeWait
+	sockstatStats.TCPTimeWaitUsedPercentage = getLimitPercentage(sockstat.TCPTimeWait, current.sockstat.maxTimeWait)
+	sockstatStats.TCPMemory = sockstat.TCPMemory
+	if len(current.sockstat.tcpMem) == 3 {
+		sockstatStats.TCPMemoryPressure = current.sockstat.tcpMem[1]
+		sockstatStats.TCPMemoryMax = current.sockstat.tcpMem[2]
+		sockstatStats.TCPMemoryUsedPercentage = getLimitPercentage(sockstat.TCPMemory, current.sockstat.tcpMem[2])
+	}
+	sockstatStats.UDPInUse = sockstat.UDPInUse
+	sockstatStats.UDP6InUse = sockstat.UDP6InUse
+	sockstatStats.UDPMemory = sockstat.UDPMemory
+	if len(current.sockstat.udpMem) == 3 {
+		sockstatStats.UDPMemoryPressure = current.sockstat.udpMem[1]
+		sockstatStats.UDPMemoryMax = current.sockstat.udpMem[2]
+		sockstatStats.UDPMemoryUsedPercentage = getLimitPercentage(sockstat.UDPMemory, current.sockstat.udpMem[2])
+	}
+	sockstatStats.RawInUse = sockstat.RAWInUse
+	sockstatStats.Raw6InUse = sockstat.RAW6InUse
+	sockstatStats.FragMemory = sockstat.FRAGMemory
+	sockstatStats.Frag6Memory = sockstat.FRAG6Memory
+	return &sockstatStats
+}
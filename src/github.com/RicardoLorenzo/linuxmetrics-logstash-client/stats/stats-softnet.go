@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+/**
+ * A line of /proc/net/softnet_stat per online CPU, hexadecimal columns:
+ *
+ *   0 processed, packets taken from the backlog and NAPI
+ *   1 dropped, backlog full (net.core.netdev_max_backlog)
+ *   2 time_squeeze, budget or time exhausted with work left
+ *   9 received_rps, inter-processor interrupts of RPS/RFS
+ *  10 flow_limit_count
+ *  11 backlog_len (5.10+)
+ *  12 cpu id (5.10+), lines skip offline CPUs
+ */
+type softnetLine struct {
+	cpu         string
+	processed   uint64
+	dropped     uint64
+	timeSqueeze uint64
+	receivedRps uint64
+	flowLimit   uint64
+	backlog     uint64
+}
+
+func readSoftnetStat(path string) []*softnetLine {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	lines := []*softnetLine{}
+	for index, row := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(row)
+		if len(fields) < 11 {
+			continue
+		}
+		values := make([]uint64, len(fields))
+		for i, field := range fields {
+			values[i], _ = strconv.ParseUint(field, 16, 64)
+		}
+		line := softnetLine{cpu: "cpu" + strconv.Itoa(index)}
+		if len(values) > 12 {
+			line.cpu = "cpu" + strconv.FormatUint(values[12], 10)
+		}
+		line.processed = values[0]
+		line.dropped = values[1]
+		line.timeSqueeze = values[2]
+		line.receivedRps = values[9]
+		line.flowLimit = values[10]
+		if len(values) > 11 {
+			line.backlog = values[11]
+		}
+		lines = append(lines, &line)
+	}
+	return lines
+}
+
+type SoftnetStats struct {
+	Cpu             string  `json:"cpu" kind:"label"`
+	ProcessedRate   float64 `json:"processed_rate" kind:"counter" unit:"per_second"`
+	DroppedRate     float64 `json:"dropped_rate" kind:"counter" unit:"per_second"`
+	TimeSqueezeRate float64 `json:"time_squeeze_rate" kind:"counter" unit:"per_second"`
+	ReceivedRpsRate float64 `json:"received_rps_rate" kind:"counter" unit:"per_second"`
+	FlowLimitRate   float64 `json:"flow_limit_rate" kind:"counter" unit:"per_second"`
+	BacklogLength   uint64  `json:"backlog_length" kind:"gauge" unit:"packets"`
+}
+
+func (softnetStats *SoftnetStats) add(other *SoftnetStats) {
+	softnetStats.ProcessedRate += other.ProcessedRate
+	softnetStats.DroppedRate += other.DroppedRate
+	softnetStats.TimeSqueezeRate += other.TimeSqueezeRate
+	softnetStats.ReceivedRpsRate += other.ReceivedRpsRate
+	softnetStats.FlowLimitRate += other.FlowLimitRate
+	softnetStats.BacklogLength += other.BacklogLength
+}
+
+/**
+ * Packet processing of the kernel between the NIC and the sockets.
+ * Interface drops are lost by the NIC, dropped here means a full input
+ * backlog, and time squeezes a net_rx softirq cut short by its budget.
+ */
+type LinuxSoftnetStats struct {
+	All  *SoftnetStats   `json:"all"`
+	Cpus []*SoftnetStats `json:"cpus"`
+}
+
+func NewLinuxSoftnetStats() *LinuxSoftnetStats {
+	previous, current := SharedStatsPeriod.GetStatsSamples()
+	seconds := current.getElapsedSeconds(previous)
+	if len(current.softnet) == 0 {
+		return nil
+	}
+
+	prevLines := make(map[string]*softnetLine)
+	for _, line := range previous.softnet {
+		prevLines[line.cpu] = line
+	}
+
+	softnetStats := LinuxSoftnetStats{}
+	softnetStats.All = &SoftnetStats{Cpu: "all"}
+	for _, curr := range current.softnet {
+		prev, present := prevLines[curr.cpu]
+		if !present {
+			prev = curr
+		}
+		cpuStats := SoftnetStats{Cpu: curr.cpu}
+		cpuStats.ProcessedRate = getCounterRate(prev.processed, curr.processed, seconds)
+		cpuStats.DroppedRate = getCounterRate(prev.dropped, curr.dropped, seconds)
+		cpuStats.TimeSqueezeRate = getCounterRate(prev.timeSqueeze, curr.timeSqueeze, seconds)
+		cpuStats.ReceivedRpsRate = getCounterRate(prev.receivedRps, curr.receivedRps, seconds)
+		cpuStats.FlowLimitRate = getCounterRate(prev.flowLimit, curr.flowLimit, seconds)
+		cpuStats.BacklogLength = curr.backlog
+		softnetStats.All.add(&cpuStats)
+		softnetStats.Cpus = append(softnetStats.Cpus, &cpuStats)
+	}
+	return &softnetStats
+}
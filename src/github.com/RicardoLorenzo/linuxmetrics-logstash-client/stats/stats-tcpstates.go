@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number of local ports reported by connection count
+var TCPTopPortsCount int = 10
+
+/**
+ * Socket states as found in the "st" column of /proc/net/tcp
+ *
+ * https://github.com/torvalds/linux/blob/master/include/net/tcp_states.h
+ */
+const tcpStateListen uint8 = 0x0A
+
+var tcpStateNames = map[uint8]string{
+	0x01: "established",
+	0x02: "syn_sent",
+	0x03: "syn_recv",
+	0x04: "fin_wait1",
+	0x05: "fin_wait2",
+	0x06: "time_wait",
+	0x07: "close",
+	0x08: "close_wait",
+	0x09: "last_ack",
+	0x0A: "listen",
+	0x0B: "closing",
+	0x0C: "new_syn_recv",
+}
+
+type LocalPortStats struct {
+	Port        uint64 `json:"port" kind:"label"`
+	Connections uint64 `json:"connections" kind:"gauge" unit:"count"`
+}
+
+/**
+ * Socket state histogram over IPv4 and IPv6 sockets. For listening
+ * sockets the rx_queue column is the current accept queue length, a
+ * non-empty queue means the application is not accepting fast enough.
+ */
+type LinuxTCPSocketStats struct {
+	States           map[string]uint64 `json:"states" kind:"gauge" unit:"count"`
+	ListenSockets    uint64            `json:"listen_sockets" kind:"gauge" unit:"count"`
+	ListenBacklogged uint64            `json:"listen_backlogged" kind:"gauge" unit:"count"`
+	TopLocalPorts    []*LocalPortStats `json:"top_local_ports"`
+}
+
+func (socketStats *LinuxTCPSocketStats) getPort(address string) (uint64, error) {
+	return strconv.ParseUint(address[strings.LastIndex(address, ":")+1:], 10, 16)
+}
+
+func (socketStats *LinuxTCPSocketStats) getStateName(state uint8) string {
+	if name, present := tcpStateNames[state]; present {
+		return name
+	}
+	return "unknown"
+}
+
+func NewLinuxTCPSocketStats() *LinuxTCPSocketStats {
+	socketStats := LinuxTCPSocketStats{}
+	socketStats.States = make(map[string]uint64)
+
+	_, current := SharedStatsPeriod.GetStatsSamples()
+
+	// Every state is always reported so dashboards don't have gaps
+	for _, name := range tcpStateNames {
+		socketStats.States[name] = 0
+	}
+
+	connections := make(map[uint64]uint64)
+	for i, _ := range current.tcpsockets {
+		socket := current.tcpsockets[i].NetSocket
+		socketStats.States[socketStats.getStateName(socket.Status)]++
+
+		if socket.Status == tcpStateListen {
+			socketStats.ListenSockets++
+			if socket.RxQueue > 0 {
+				socketStats.ListenBacklogged++
+			}
+			continue
+		}
+		if port, err := socketStats.getPort(socket.LocalAddress); err == nil {
+			connections[port]++
+		}
+	}
+
+	for port, count := range connections {
+		socketStats.TopLocalPorts = append(socketStats.TopLocalPorts, &LocalPortStats{port, count})
+	}
+	sort.Slice(socketStats.TopLocalPorts, func(i, j int) bool {
+		if socketStats.TopLocalPorts[i].Connections == socketStats.TopLocalPorts[j].Connections {
+			return socketStats.TopLocalPorts[i].Port < socketStats.TopLocalPorts[j].Port
+		}
+		return socketStats.TopLocalPorts[i].Connections > socketStats.TopLocalPorts[j].Connections
+	})
+	if TCPTopPortsCount >= 0 && len(socketStats.TopLocalPorts) > TCPTopPortsCount {
+		socketStats.TopLocalPorts = socketStats.TopLocalPorts[:TCPTopPortsCount]
+	}
+
+	return &socketStats
+}
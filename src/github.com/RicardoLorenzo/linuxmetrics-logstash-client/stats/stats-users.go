@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The passwd file is read on every sample, a missing one is logged once
+var userNamesErrorOnce sync.Once
+
+/**
+ * User names by uid from <HostRootPath>/etc/passwd, so that containerized
+ * collectors resolve the host users. Users only known to NSS (LDAP, sssd)
+ * are missing and reported by uid.
+ */
+func readUserNames() map[uint64]string {
+	users := make(map[uint64]string)
+
+	data, err := ioutil.ReadFile(filepath.Join(HostRootPath, "etc", "passwd"))
+	if err != nil {
+		userNamesErrorOnce.Do(func() {
+			log.Println("Cannot read the host users, reporting uids -", err)
+		})
+		return users
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// name:password:uid:gid:gecos:home:shell
+		tokens := strings.Split(line, ":")
+		if len(tokens) < 3 {
+			continue
+		}
+		uid, err := strconv.ParseUint(tokens[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		if _, present := users[uid]; !present {
+			users[uid] = tokens[0]
+		}
+	}
+	return users
+}
+
+func getUserName(users map[uint64]string, uid uint64) string {
+	if name, present := users[uid]; present {
+		return name
+	}
+	return strconv.FormatUint(uid, 10)
+}
@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadUserNamesHostRoot(t *testing.T) {
+	directory, err := ioutil.TempDir("", "hostroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	if err := os.Mkdir(filepath.Join(directory, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	passwd := "root:x:0:0:root:/root:/bin/bash\n" +
+		"# comment\n" +
+		"www-data:x:33:33:www-data:/var/www:/usr/sbin/nologin\n" +
+		"toor:x:0:0:duplicate:/root:/bin/sh\n"
+	if err := ioutil.WriteFile(filepath.Join(directory, "etc", "passwd"), []byte(passwd), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(path string) { HostRootPath = path }(HostRootPath)
+	// The helm chart passes -host-root /host/root, without a trailing slash
+	for _, root := range []string{directory, directory + "/"} {
+		HostRootPath = root
+		users := readUserNames()
+		if getUserName(users, 0) != "root" || getUserName(users, 33) != "www-data" || getUserName(users, 1000) != "1000" {
+			t.Errorf("host root %q gives users %v", root, users)
+		}
+	}
+}
@@ -1,21 +1,21 @@
 package stats
 
 type LinuxVMStats struct {
-	PgFree uint64 `json:"pgfree"`
-	PgpgIn uint64 `json:"pgpgin"`
-	PgpgOut uint64 `json:"pgpgout"`
-	PswpIn uint64 `json:"pswpin"`
-	PswpOut uint64 `json:"pswpout"`
-	PgFault uint64 `json:"pgfault"`
-	PgMajFault uint64 `json:"pgmajfault"`
-	NrMLock uint64 `json:"nr_mlock"`
-	NrShMem uint64 `json:"nr_shmem"`
-	NrDirty uint64 `json:"nr_dirty"`
-	NrPageTablePages uint64 `json:"nr_page_table_pages"`
-	NrSlab uint64 `json:"nr_slab"`
-	NrMapped uint64 `json:"nr_mapped"`
-	NrFreePages uint64 `json:"nr_free_pages"`
-	NrAnonPages uint64 `json:"nr_anon_pages"`
+	PgFree uint64 `json:"pgfree" kind:"counter" unit:"pages"`
+	PgpgIn uint64 `json:"pgpgin" kind:"counter" unit:"kilobytes"`
+	PgpgOut uint64 `json:"pgpgout" kind:"counter" unit:"kilobytes"`
+	PswpIn uint64 `json:"pswpin" kind:"counter" unit:"pages"`
+	PswpOut uint64 `json:"pswpout" kind:"counter" unit:"pages"`
+	PgFault uint64 `json:"pgfault" kind:"counter" unit:"count"`
+	PgMajFault uint64 `json:"pgmajfault" kind:"counter" unit:"count"`
+	NrMLock uint64 `json:"nr_mlock" kind:"gauge" unit:"pages"`
+	NrShMem uint64 `json:"nr_shmem" kind:"gauge" unit:"pages"`
+	NrDirty uint64 `json:"nr_dirty" kind:"gauge" unit:"pages"`
+	NrPageTablePages uint64 `json:"nr_page_table_pages" kind:"gauge" unit:"pages"`
+	NrSlab uint64 `json:"nr_slab" kind:"gauge" unit:"pages"`
+	NrMapped uint64 `json:"nr_mapped" kind:"gauge" unit:"pages"`
+	NrFreePages uint64 `json:"nr_free_pages" kind:"gauge" unit:"pages"`
+	NrAnonPages uint64 `json:"nr_anon_pages" kind:"gauge" unit:"pages"`
 }
 
 func NewLinuxVMStats() *LinuxVMStats {